@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ACL is source address allow and deny lists of client connections, deny takes precedence,
+// an empty allow list allows all addresses not denied
+type ACL struct {
+	Allow cidrList `yaml:"allow"`
+	Deny  cidrList `yaml:"deny"`
+}
+
+// Allowed returns true if ip is allowed by acl
+func (acl ACL) Allowed(ip net.IP) bool {
+	if acl.Deny.contains(ip) {
+		return false
+	}
+	return len(acl.Allow) == 0 || acl.Allow.contains(ip)
+}
+
+// cidrList implements flag.Value and yaml.Unmarshaler, used for repeatable -allow and -deny flags
+// and allow and deny keys in config file; a single IP address is treated as a host prefix
+type cidrList []*net.IPNet
+
+func (cl cidrList) contains(ip net.IP) bool {
+	for _, n := range cl {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cl *cidrList) String() string {
+	if cl == nil {
+		return ""
+	}
+	var r []string
+	for _, n := range *cl {
+		r = append(r, n.String())
+	}
+	return strings.Join(r, ",")
+}
+
+func (cl *cidrList) Set(s string) error {
+	n, err := parseCIDR(s)
+	if err != nil {
+		return err
+	}
+	*cl = append(*cl, n)
+	return nil
+}
+
+func (cl *cidrList) UnmarshalYAML(value *yaml.Node) error {
+	var l []string
+	if err := value.Decode(&l); err != nil {
+		return err
+	}
+	*cl = nil
+	for _, s := range l {
+		n, err := parseCIDR(s)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", value.Line, err)
+		}
+		*cl = append(*cl, n)
+	}
+	return nil
+}
+
+// parseCIDR parses s as a CIDR prefix or an IP address
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR %v", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address or CIDR %v", s)
+	}
+	return n, nil
+}
+
+// destList implements flag.Value and yaml.Unmarshaler, it is the allowlist of destinations worker connects to
+// on behalf of reflector; an entry is host:port, or CIDR or IP address with optional port, e.g. 10.0.0.0/8:22
+type destList []*destEntry
+
+type destEntry struct {
+	host string     //host name or IP address, empty if net is used
+	net  *net.IPNet //allowed network, nil if host is used
+	port string     //empty means any port
+}
+
+func (e *destEntry) String() string {
+	host := e.host
+	if e.net != nil {
+		host = e.net.String()
+	}
+	if e.port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, e.port)
+}
+
+// parseDestEntry parses s in format of host:port, CIDR:port, CIDR or IP address
+func parseDestEntry(s string) (*destEntry, error) {
+	e := new(destEntry)
+	host := s
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		host, e.port = h, p
+	}
+	if n, err := parseCIDR(host); err == nil {
+		e.net = n
+		return e, nil
+	}
+	if e.port == "" || host == "" {
+		return nil, fmt.Errorf("invalid destination %v, expect host:port, CIDR[:port] or IP address", s)
+	}
+	e.host = strings.ToLower(host)
+	return e, nil
+}
+
+func (dl *destList) String() string {
+	if dl == nil {
+		return ""
+	}
+	var r []string
+	for _, e := range *dl {
+		r = append(r, e.String())
+	}
+	return strings.Join(r, ",")
+}
+
+func (dl *destList) Set(s string) error {
+	e, err := parseDestEntry(s)
+	if err != nil {
+		return err
+	}
+	*dl = append(*dl, e)
+	return nil
+}
+
+func (dl *destList) UnmarshalYAML(value *yaml.Node) error {
+	var l []string
+	if err := value.Decode(&l); err != nil {
+		return err
+	}
+	*dl = nil
+	for _, s := range l {
+		e, err := parseDestEntry(s)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", value.Line, err)
+		}
+		*dl = append(*dl, e)
+	}
+	return nil
+}
+
+// check returns the address to connect to if destination addr is allowed by dl; a host name not listed as is
+// is resolved and the first allowed address is returned, so that the checked address is the one connected to;
+// an empty dl allows everything
+func (dl destList) check(addr string) (string, error) {
+	if len(dl) == 0 {
+		return addr, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid destination %v, %w", addr, err)
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		for _, e := range dl {
+			if e.host == strings.ToLower(host) && e.port == port {
+				return addr, nil
+			}
+		}
+		if ips, err = net.LookupIP(host); err != nil {
+			return "", fmt.Errorf("failed to resolve destination %v, %w", addr, err)
+		}
+	}
+	for _, ip := range ips {
+		for _, e := range dl {
+			if e.net != nil && e.net.Contains(ip) && (e.port == "" || e.port == port) {
+				return net.JoinHostPort(ip.String(), port), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("destination %v is not allowed", addr)
+}
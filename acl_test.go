@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustDestList(t *testing.T, entries ...string) destList {
+	var dl destList
+	for _, s := range entries {
+		if err := dl.Set(s); err != nil {
+			t.Fatalf("invalid destination %v, %v", s, err)
+		}
+	}
+	return dl
+}
+
+func TestDestListCheck(t *testing.T) {
+	dl := mustDestList(t, "10.0.0.0/8:22", "192.168.1.1", "db.internal:5432", "[2001:db8::/32]:443")
+	cases := []struct {
+		name string
+		addr string
+		want string //empty means not allowed
+	}{
+		{name: "CIDR with port", addr: "10.1.2.3:22", want: "10.1.2.3:22"},
+		{name: "CIDR with other port", addr: "10.1.2.3:23"},
+		{name: "IP any port", addr: "192.168.1.1:8080", want: "192.168.1.1:8080"},
+		{name: "other IP", addr: "192.168.1.2:8080"},
+		{name: "listed host", addr: "db.internal:5432", want: "db.internal:5432"},
+		{name: "listed host case insensitive", addr: "DB.Internal:5432", want: "DB.Internal:5432"},
+		{name: "listed host other port", addr: "db.internal:5433"},
+		{name: "IPv6 CIDR", addr: "[2001:db8::1]:443", want: "[2001:db8::1]:443"},
+		{name: "IPv6 outside CIDR", addr: "[2001:db9::1]:443"},
+		{name: "resolved host", addr: "localhost:22"},
+		{name: "link local", addr: "169.254.169.254:80"},
+		{name: "no port", addr: "10.1.2.3"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := dl.check(c.addr)
+			if c.want == "" && err == nil {
+				t.Errorf("%v is allowed as %v, expect refused", c.addr, got)
+			}
+			if c.want != "" && (err != nil || got != c.want) {
+				t.Errorf("got %q and error %v, expect %q", got, err, c.want)
+			}
+		})
+	}
+}
+
+func TestDestListCheckResolved(t *testing.T) {
+	dl := mustDestList(t, "127.0.0.0/8:22")
+	got, err := dl.check("localhost:22")
+	if err != nil {
+		t.Skipf("localhost doesn't resolve to an allowed address, %v", err)
+	}
+	if !strings.HasPrefix(got, "127.") {
+		t.Errorf("got %q, expect the resolved address", got)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"rproxy/api"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ctlTimeout = 10 * time.Second
+)
+
+func (refl *Reflector) ListCrossConnections(ctx context.Context, req *api.Empty) (*api.CrossConnectionList, error) {
+	if err := refl.authAdmin(ctx); err != nil {
+		return nil, err
+	}
+	refl.workerLock.RLock()
+	defer refl.workerLock.RUnlock()
+	r := &api.CrossConnectionList{}
+	for _, cc := range refl.CrossConnections {
+		st := cc.Stats()
+		info := &api.CrossConnectionInfo{
+			ID:         uint32(cc.ID),
+			WorkerID:   cc.WorkerID,
+			Tunnel:     cc.Tunnel,
+			ClientAddr: cc.Conn1.RemoteAddr().String(),
+			StartTime:  st.StartTime.UnixNano(),
+			BytesIn:    st.BytesIn,
+			BytesOut:   st.BytesOut,
+		}
+		if !st.LastActivity.IsZero() {
+			info.LastActivity = st.LastActivity.UnixNano()
+		}
+		if cc.Conn2 != nil {
+			info.WorkerDataAddr = cc.Conn2.RemoteAddr().String()
+		}
+		r.CrossConnections = append(r.CrossConnections, info)
+	}
+	return r, nil
+}
+
+func (refl *Reflector) KillCrossConnection(ctx context.Context, req *api.KillCrossConnectionReq) (*api.Empty, error) {
+	if err := refl.authAdmin(ctx); err != nil {
+		return nil, err
+	}
+	refl.workerLock.Lock()
+	defer refl.workerLock.Unlock()
+	cc, ok := refl.CrossConnections[int(req.ID)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "crossconnection %d not found", req.ID)
+	}
+	cc.setCloseReason("killed by admin")
+	refl.closeCC(cc)
+	return &api.Empty{}, nil
+}
+
+// runCtl implements the ctl subcommand, which calls reflector admin API
+func runCtl(args []string) error {
+	fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+	apiaddr := fs.String("reflapi", fmt.Sprintf("127.0.0.1:%d", defaultReflAPIListenPort), "reflector api tcp address")
+	secret := fs.String("adminsecret", "", "admin secret of reflector")
+	tlsCert := fs.String("tlscert", "", "TLS certificate file, enables TLS")
+	tlsKey := fs.String("tlskey", "", "TLS private key file")
+	tlsCA := fs.String("tlsca", "", "TLS CA certificate file, used to verify reflector certificate")
+	tlsName := fs.String("tlsname", "", "reflector name in its TLS certificate, default is host of reflector address")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of ctl: rproxy ctl [flags] list|kill <id>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	creds := grpc.WithInsecure()
+	if *tlsCert != "" || *tlsKey != "" || *tlsCA != "" {
+		conf, err := loadTLSConfig(*tlsCert, *tlsKey, *tlsCA, false)
+		if err != nil {
+			return err
+		}
+		conf.ServerName = *tlsName
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(clientTLSConfig(conf, *apiaddr)))
+	}
+	conn, err := grpc.Dial(*apiaddr, creds)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	clnt := api.NewRProxyAPIClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), ctlTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, mdAdminSecret, *secret)
+	switch fs.Arg(0) {
+	case "list":
+		list, err := clnt.ListCrossConnections(ctx, &api.Empty{})
+		if err != nil {
+			return err
+		}
+		printCrossConnections(list)
+	case "kill":
+		id, err := strconv.ParseUint(fs.Arg(1), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid crossconnection ID %v, %w", fs.Arg(1), err)
+		}
+		if _, err = clnt.KillCrossConnection(ctx, &api.KillCrossConnectionReq{ID: uint32(id)}); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	return nil
+}
+
+func printCrossConnections(list *api.CrossConnectionList) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tWORKER\tTUNNEL\tCLIENT\tWORKER DATA\tSTART\tIDLE\tBYTES IN\tBYTES OUT")
+	now := time.Now()
+	for _, cc := range list.CrossConnections {
+		last := cc.LastActivity
+		if last == 0 {
+			last = cc.StartTime
+		}
+		idle := now.Sub(time.Unix(0, last)).Round(time.Second)
+		fmt.Fprintf(tw, "%d\t%v\t%v\t%v\t%v\t%v\t%v\t%d\t%d\n", cc.ID, cc.WorkerID, cc.Tunnel, cc.ClientAddr,
+			cc.WorkerDataAddr, time.Unix(0, cc.StartTime).Format(time.RFC3339), idle, cc.BytesIn, cc.BytesOut)
+	}
+	tw.Flush()
+}
@@ -27,6 +27,76 @@ const (
 // of the legacy proto package is being used.
 const _ = proto.ProtoPackageIsVersion4
 
+type CrossStatus int32
+
+const (
+	CrossStatus_CROSS_OK                      CrossStatus = 0
+	CrossStatus_CROSS_SERVER_DIAL_FAILED      CrossStatus = 1 // worker can't connect to the server
+	CrossStatus_CROSS_REFL_DIAL_FAILED        CrossStatus = 2 // worker can't create data connection to reflector
+	CrossStatus_CROSS_POOL_CONN_NOT_FOUND     CrossStatus = 3 // pool data connection bound to the cross connection is not found on worker
+	CrossStatus_CROSS_DEST_NOT_ALLOWED        CrossStatus = 4 // target from reflector is not in worker's destination allowlist
+	CrossStatus_CROSS_SERVER_REFUSED          CrossStatus = 5 // server refuses the connection
+	CrossStatus_CROSS_SERVER_HOST_UNREACHABLE CrossStatus = 6 // server host can't be resolved or reached
+	CrossStatus_CROSS_SERVER_NET_UNREACHABLE  CrossStatus = 7 // server network can't be reached
+	CrossStatus_CROSS_SERVER_TIMEOUT          CrossStatus = 8 // connecting to server times out
+	CrossStatus_CROSS_NO_WORKER               CrossStatus = 9 // no worker of the tunnel is signed on, only used by reflector
+)
+
+// Enum value maps for CrossStatus.
+var (
+	CrossStatus_name = map[int32]string{
+		0: "CROSS_OK",
+		1: "CROSS_SERVER_DIAL_FAILED",
+		2: "CROSS_REFL_DIAL_FAILED",
+		3: "CROSS_POOL_CONN_NOT_FOUND",
+		4: "CROSS_DEST_NOT_ALLOWED",
+		5: "CROSS_SERVER_REFUSED",
+		6: "CROSS_SERVER_HOST_UNREACHABLE",
+		7: "CROSS_SERVER_NET_UNREACHABLE",
+		8: "CROSS_SERVER_TIMEOUT",
+		9: "CROSS_NO_WORKER",
+	}
+	CrossStatus_value = map[string]int32{
+		"CROSS_OK":                      0,
+		"CROSS_SERVER_DIAL_FAILED":      1,
+		"CROSS_REFL_DIAL_FAILED":        2,
+		"CROSS_POOL_CONN_NOT_FOUND":     3,
+		"CROSS_DEST_NOT_ALLOWED":        4,
+		"CROSS_SERVER_REFUSED":          5,
+		"CROSS_SERVER_HOST_UNREACHABLE": 6,
+		"CROSS_SERVER_NET_UNREACHABLE":  7,
+		"CROSS_SERVER_TIMEOUT":          8,
+		"CROSS_NO_WORKER":               9,
+	}
+)
+
+func (x CrossStatus) Enum() *CrossStatus {
+	p := new(CrossStatus)
+	*p = x
+	return p
+}
+
+func (x CrossStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CrossStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_enumTypes[0].Descriptor()
+}
+
+func (CrossStatus) Type() protoreflect.EnumType {
+	return &file_api_proto_enumTypes[0]
+}
+
+func (x CrossStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CrossStatus.Descriptor instead.
+func (CrossStatus) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{0}
+}
+
 type Empty struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -65,18 +135,205 @@ func (*Empty) Descriptor() ([]byte, []int) {
 	return file_api_proto_rawDescGZIP(), []int{0}
 }
 
+type SignonReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	WorkerID string `protobuf:"bytes,1,opt,name=WorkerID,proto3" json:"WorkerID,omitempty"`
+	Secret   string `protobuf:"bytes,2,opt,name=Secret,proto3" json:"Secret,omitempty"` // pre-shared secret between reflector and workers
+}
+
+func (x *SignonReq) Reset() {
+	*x = SignonReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SignonReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignonReq) ProtoMessage() {}
+
+func (x *SignonReq) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignonReq.ProtoReflect.Descriptor instead.
+func (*SignonReq) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SignonReq) GetWorkerID() string {
+	if x != nil {
+		return x.WorkerID
+	}
+	return ""
+}
+
+func (x *SignonReq) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type SignonResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DataToken         []byte `protobuf:"bytes,1,opt,name=DataToken,proto3" json:"DataToken,omitempty"` // presented by worker in preamble of multiplexed and pool data connections
+	SessionID         string `protobuf:"bytes,2,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
+	HeartbeatInterval uint32 `protobuf:"varint,3,opt,name=HeartbeatInterval,proto3" json:"HeartbeatInterval,omitempty"` // in milliseconds, 0 means heartbeat is disabled
+	HeartbeatMiss     uint32 `protobuf:"varint,4,opt,name=HeartbeatMiss,proto3" json:"HeartbeatMiss,omitempty"`         // number of missed heartbeats before peer is considered dead
+}
+
+func (x *SignonResp) Reset() {
+	*x = SignonResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SignonResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignonResp) ProtoMessage() {}
+
+func (x *SignonResp) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignonResp.ProtoReflect.Descriptor instead.
+func (*SignonResp) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SignonResp) GetDataToken() []byte {
+	if x != nil {
+		return x.DataToken
+	}
+	return nil
+}
+
+func (x *SignonResp) GetSessionID() string {
+	if x != nil {
+		return x.SessionID
+	}
+	return ""
+}
+
+func (x *SignonResp) GetHeartbeatInterval() uint32 {
+	if x != nil {
+		return x.HeartbeatInterval
+	}
+	return 0
+}
+
+func (x *SignonResp) GetHeartbeatMiss() uint32 {
+	if x != nil {
+		return x.HeartbeatMiss
+	}
+	return 0
+}
+
+type WorkerReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	WorkerID  string `protobuf:"bytes,1,opt,name=WorkerID,proto3" json:"WorkerID,omitempty"`
+	SessionID string `protobuf:"bytes,2,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
+}
+
+func (x *WorkerReq) Reset() {
+	*x = WorkerReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WorkerReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkerReq) ProtoMessage() {}
+
+func (x *WorkerReq) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkerReq.ProtoReflect.Descriptor instead.
+func (*WorkerReq) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WorkerReq) GetWorkerID() string {
+	if x != nil {
+		return x.WorkerID
+	}
+	return ""
+}
+
+func (x *WorkerReq) GetSessionID() string {
+	if x != nil {
+		return x.SessionID
+	}
+	return ""
+}
+
 type CreateWorkerCrossReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID uint32 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	ID         uint32 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Tunnel     string `protobuf:"bytes,2,opt,name=Tunnel,proto3" json:"Tunnel,omitempty"`
+	Target     string `protobuf:"bytes,3,opt,name=Target,proto3" json:"Target,omitempty"`
+	PoolConnID uint32 `protobuf:"varint,4,opt,name=PoolConnID,proto3" json:"PoolConnID,omitempty"` // non-zero if reflector has bound the cross connection to this idle pool data connection
+	Token      []byte `protobuf:"bytes,5,opt,name=Token,proto3" json:"Token,omitempty"`            // one-time token presented by worker on the data connection or stream of the cross connection
+	Dynamic    bool   `protobuf:"varint,6,opt,name=Dynamic,proto3" json:"Dynamic,omitempty"`       // Target is requested by client, e.g. via SOCKS5, worker's own targets don't apply
+	UDP        bool   `protobuf:"varint,7,opt,name=UDP,proto3" json:"UDP,omitempty"`               // Target is a UDP server, datagrams are length-framed on the data connection
 }
 
 func (x *CreateWorkerCrossReq) Reset() {
 	*x = CreateWorkerCrossReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_msgTypes[1]
+		mi := &file_api_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -89,7 +346,7 @@ func (x *CreateWorkerCrossReq) String() string {
 func (*CreateWorkerCrossReq) ProtoMessage() {}
 
 func (x *CreateWorkerCrossReq) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[1]
+	mi := &file_api_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -102,7 +359,7 @@ func (x *CreateWorkerCrossReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateWorkerCrossReq.ProtoReflect.Descriptor instead.
 func (*CreateWorkerCrossReq) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{1}
+	return file_api_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *CreateWorkerCrossReq) GetID() uint32 {
@@ -112,19 +369,314 @@ func (x *CreateWorkerCrossReq) GetID() uint32 {
 	return 0
 }
 
+func (x *CreateWorkerCrossReq) GetTunnel() string {
+	if x != nil {
+		return x.Tunnel
+	}
+	return ""
+}
+
+func (x *CreateWorkerCrossReq) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *CreateWorkerCrossReq) GetPoolConnID() uint32 {
+	if x != nil {
+		return x.PoolConnID
+	}
+	return 0
+}
+
+func (x *CreateWorkerCrossReq) GetToken() []byte {
+	if x != nil {
+		return x.Token
+	}
+	return nil
+}
+
+func (x *CreateWorkerCrossReq) GetDynamic() bool {
+	if x != nil {
+		return x.Dynamic
+	}
+	return false
+}
+
+func (x *CreateWorkerCrossReq) GetUDP() bool {
+	if x != nil {
+		return x.UDP
+	}
+	return false
+}
+
+type HeartbeatMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seq uint64 `protobuf:"varint,1,opt,name=Seq,proto3" json:"Seq,omitempty"`
+}
+
+func (x *HeartbeatMsg) Reset() {
+	*x = HeartbeatMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HeartbeatMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatMsg) ProtoMessage() {}
+
+func (x *HeartbeatMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatMsg.ProtoReflect.Descriptor instead.
+func (*HeartbeatMsg) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HeartbeatMsg) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type CrossConnectionInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ID             uint32 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	WorkerID       string `protobuf:"bytes,2,opt,name=WorkerID,proto3" json:"WorkerID,omitempty"`
+	Tunnel         string `protobuf:"bytes,3,opt,name=Tunnel,proto3" json:"Tunnel,omitempty"`
+	ClientAddr     string `protobuf:"bytes,4,opt,name=ClientAddr,proto3" json:"ClientAddr,omitempty"`
+	WorkerDataAddr string `protobuf:"bytes,5,opt,name=WorkerDataAddr,proto3" json:"WorkerDataAddr,omitempty"` // empty if the cross connection is waiting for worker data connection
+	StartTime      int64  `protobuf:"varint,6,opt,name=StartTime,proto3" json:"StartTime,omitempty"`          // unix time in nanoseconds
+	BytesIn        uint64 `protobuf:"varint,7,opt,name=BytesIn,proto3" json:"BytesIn,omitempty"`              // bytes from client to worker
+	BytesOut       uint64 `protobuf:"varint,8,opt,name=BytesOut,proto3" json:"BytesOut,omitempty"`            // bytes from worker to client
+	LastActivity   int64  `protobuf:"varint,9,opt,name=LastActivity,proto3" json:"LastActivity,omitempty"`    // unix time in nanoseconds of last data transferred, 0 if none
+}
+
+func (x *CrossConnectionInfo) Reset() {
+	*x = CrossConnectionInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CrossConnectionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrossConnectionInfo) ProtoMessage() {}
+
+func (x *CrossConnectionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrossConnectionInfo.ProtoReflect.Descriptor instead.
+func (*CrossConnectionInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CrossConnectionInfo) GetID() uint32 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
+}
+
+func (x *CrossConnectionInfo) GetWorkerID() string {
+	if x != nil {
+		return x.WorkerID
+	}
+	return ""
+}
+
+func (x *CrossConnectionInfo) GetTunnel() string {
+	if x != nil {
+		return x.Tunnel
+	}
+	return ""
+}
+
+func (x *CrossConnectionInfo) GetClientAddr() string {
+	if x != nil {
+		return x.ClientAddr
+	}
+	return ""
+}
+
+func (x *CrossConnectionInfo) GetWorkerDataAddr() string {
+	if x != nil {
+		return x.WorkerDataAddr
+	}
+	return ""
+}
+
+func (x *CrossConnectionInfo) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *CrossConnectionInfo) GetBytesIn() uint64 {
+	if x != nil {
+		return x.BytesIn
+	}
+	return 0
+}
+
+func (x *CrossConnectionInfo) GetBytesOut() uint64 {
+	if x != nil {
+		return x.BytesOut
+	}
+	return 0
+}
+
+func (x *CrossConnectionInfo) GetLastActivity() int64 {
+	if x != nil {
+		return x.LastActivity
+	}
+	return 0
+}
+
+type CrossConnectionList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CrossConnections []*CrossConnectionInfo `protobuf:"bytes,1,rep,name=CrossConnections,proto3" json:"CrossConnections,omitempty"`
+}
+
+func (x *CrossConnectionList) Reset() {
+	*x = CrossConnectionList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CrossConnectionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrossConnectionList) ProtoMessage() {}
+
+func (x *CrossConnectionList) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrossConnectionList.ProtoReflect.Descriptor instead.
+func (*CrossConnectionList) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CrossConnectionList) GetCrossConnections() []*CrossConnectionInfo {
+	if x != nil {
+		return x.CrossConnections
+	}
+	return nil
+}
+
+type KillCrossConnectionReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ID uint32 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+}
+
+func (x *KillCrossConnectionReq) Reset() {
+	*x = KillCrossConnectionReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KillCrossConnectionReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KillCrossConnectionReq) ProtoMessage() {}
+
+func (x *KillCrossConnectionReq) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KillCrossConnectionReq.ProtoReflect.Descriptor instead.
+func (*KillCrossConnectionReq) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *KillCrossConnectionReq) GetID() uint32 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
+}
+
 type ReportWorkerCrossReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID   uint32 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	Port uint32 `protobuf:"varint,2,opt,name=Port,proto3" json:"Port,omitempty"`
+	ID     uint32      `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Status CrossStatus `protobuf:"varint,3,opt,name=Status,proto3,enum=api.CrossStatus" json:"Status,omitempty"`
+	Error  string      `protobuf:"bytes,4,opt,name=Error,proto3" json:"Error,omitempty"` // description of the failure if Status is not CROSS_OK
 }
 
 func (x *ReportWorkerCrossReq) Reset() {
 	*x = ReportWorkerCrossReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_msgTypes[2]
+		mi := &file_api_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -137,7 +689,7 @@ func (x *ReportWorkerCrossReq) String() string {
 func (*ReportWorkerCrossReq) ProtoMessage() {}
 
 func (x *ReportWorkerCrossReq) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[2]
+	mi := &file_api_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -150,7 +702,7 @@ func (x *ReportWorkerCrossReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportWorkerCrossReq.ProtoReflect.Descriptor instead.
 func (*ReportWorkerCrossReq) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{2}
+	return file_api_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ReportWorkerCrossReq) GetID() uint32 {
@@ -160,39 +712,135 @@ func (x *ReportWorkerCrossReq) GetID() uint32 {
 	return 0
 }
 
-func (x *ReportWorkerCrossReq) GetPort() uint32 {
+func (x *ReportWorkerCrossReq) GetStatus() CrossStatus {
 	if x != nil {
-		return x.Port
+		return x.Status
 	}
-	return 0
+	return CrossStatus_CROSS_OK
+}
+
+func (x *ReportWorkerCrossReq) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
 }
 
 var File_api_proto protoreflect.FileDescriptor
 
 var file_api_proto_rawDesc = []byte{
 	0x0a, 0x09, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x61, 0x70, 0x69,
-	0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x26, 0x0a, 0x14, 0x43, 0x72, 0x65,
-	0x61, 0x74, 0x65, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x52, 0x65,
-	0x71, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49,
-	0x44, 0x22, 0x3a, 0x0a, 0x14, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x65,
+	0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x3f, 0x0a, 0x09, 0x53, 0x69, 0x67,
+	0x6e, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x12, 0x1a, 0x0a, 0x08, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x22, 0x9c, 0x01, 0x0a, 0x0a, 0x53,
+	0x69, 0x67, 0x6e, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x12, 0x1c, 0x0a, 0x09, 0x44, 0x61, 0x74,
+	0x61, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x44, 0x61,
+	0x74, 0x61, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x53, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x49, 0x44, 0x12, 0x2c, 0x0a, 0x11, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65,
+	0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x11, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72,
+	0x76, 0x61, 0x6c, 0x12, 0x24, 0x0a, 0x0d, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x4d, 0x69, 0x73, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x48, 0x65, 0x61, 0x72,
+	0x74, 0x62, 0x65, 0x61, 0x74, 0x4d, 0x69, 0x73, 0x73, 0x22, 0x45, 0x0a, 0x09, 0x57, 0x6f, 0x72,
+	0x6b, 0x65, 0x72, 0x52, 0x65, 0x71, 0x12, 0x1a, 0x0a, 0x08, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x12, 0x1c, 0x0a, 0x09, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x44, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x44,
+	0x22, 0xb8, 0x01, 0x0a, 0x14, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x57, 0x6f, 0x72, 0x6b, 0x65,
 	0x72, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x50, 0x6f, 0x72,
-	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x50, 0x6f, 0x72, 0x74, 0x32, 0xd0, 0x01,
-	0x0a, 0x09, 0x52, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x41, 0x50, 0x49, 0x12, 0x20, 0x0a, 0x06, 0x53,
-	0x69, 0x67, 0x6e, 0x6f, 0x6e, 0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74,
-	0x79, 0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x21, 0x0a,
-	0x07, 0x53, 0x69, 0x67, 0x6e, 0x6f, 0x66, 0x66, 0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
-	0x12, 0x3e, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
-	0x43, 0x72, 0x6f, 0x73, 0x73, 0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74,
-	0x79, 0x1a, 0x19, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x57, 0x6f,
-	0x72, 0x6b, 0x65, 0x72, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x52, 0x65, 0x71, 0x22, 0x00, 0x30, 0x01,
-	0x12, 0x3e, 0x0a, 0x11, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
-	0x43, 0x72, 0x6f, 0x73, 0x73, 0x12, 0x19, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x52, 0x65, 0x70, 0x6f,
-	0x72, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x52, 0x65, 0x71,
-	0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x00, 0x28, 0x01,
-	0x42, 0x0c, 0x5a, 0x0a, 0x72, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2f, 0x61, 0x70, 0x69, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x75, 0x6e,
+	0x6e, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x75, 0x6e, 0x6e, 0x65,
+	0x6c, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x50, 0x6f, 0x6f,
+	0x6c, 0x43, 0x6f, 0x6e, 0x6e, 0x49, 0x44, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x50,
+	0x6f, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x6e, 0x49, 0x44, 0x12, 0x14, 0x0a, 0x05, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12,
+	0x18, 0x0a, 0x07, 0x44, 0x79, 0x6e, 0x61, 0x6d, 0x69, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x07, 0x44, 0x79, 0x6e, 0x61, 0x6d, 0x69, 0x63, 0x12, 0x10, 0x0a, 0x03, 0x55, 0x44, 0x50,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x55, 0x44, 0x50, 0x22, 0x20, 0x0a, 0x0c, 0x48,
+	0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x10, 0x0a, 0x03, 0x53,
+	0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x53, 0x65, 0x71, 0x22, 0x99, 0x02,
+	0x0a, 0x13, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x49,
+	0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x49,
+	0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x75, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x54, 0x75, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x1e, 0x0a, 0x0a, 0x43, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x41, 0x64, 0x64, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x43,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x41, 0x64, 0x64, 0x72, 0x12, 0x26, 0x0a, 0x0e, 0x57, 0x6f, 0x72,
+	0x6b, 0x65, 0x72, 0x44, 0x61, 0x74, 0x61, 0x41, 0x64, 0x64, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0e, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x44, 0x61, 0x74, 0x61, 0x41, 0x64, 0x64,
+	0x72, 0x12, 0x1c, 0x0a, 0x09, 0x53, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x53, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12,
+	0x18, 0x0a, 0x07, 0x42, 0x79, 0x74, 0x65, 0x73, 0x49, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x07, 0x42, 0x79, 0x74, 0x65, 0x73, 0x49, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x4f, 0x75, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x4f, 0x75, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x4c, 0x61, 0x73, 0x74, 0x41, 0x63, 0x74,
+	0x69, 0x76, 0x69, 0x74, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x4c, 0x61, 0x73,
+	0x74, 0x41, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x22, 0x5b, 0x0a, 0x13, 0x43, 0x72, 0x6f,
+	0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x69, 0x73, 0x74,
+	0x12, 0x44, 0x0a, 0x10, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x10, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x28, 0x0a, 0x16, 0x4b, 0x69, 0x6c, 0x6c, 0x43, 0x72,
+	0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71,
+	0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49, 0x44,
+	0x22, 0x6c, 0x0a, 0x14, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x43, 0x72, 0x6f, 0x73, 0x73, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49, 0x44, 0x12, 0x28, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43,
+	0x72, 0x6f, 0x73, 0x73, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x03, 0x2a, 0x9e,
+	0x02, 0x0a, 0x0b, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0c,
+	0x0a, 0x08, 0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f, 0x4f, 0x4b, 0x10, 0x00, 0x12, 0x1c, 0x0a, 0x18,
+	0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f, 0x53, 0x45, 0x52, 0x56, 0x45, 0x52, 0x5f, 0x44, 0x49, 0x41,
+	0x4c, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x45, 0x44, 0x10, 0x01, 0x12, 0x1a, 0x0a, 0x16, 0x43, 0x52,
+	0x4f, 0x53, 0x53, 0x5f, 0x52, 0x45, 0x46, 0x4c, 0x5f, 0x44, 0x49, 0x41, 0x4c, 0x5f, 0x46, 0x41,
+	0x49, 0x4c, 0x45, 0x44, 0x10, 0x02, 0x12, 0x1d, 0x0a, 0x19, 0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f,
+	0x50, 0x4f, 0x4f, 0x4c, 0x5f, 0x43, 0x4f, 0x4e, 0x4e, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x46, 0x4f,
+	0x55, 0x4e, 0x44, 0x10, 0x03, 0x12, 0x1a, 0x0a, 0x16, 0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f, 0x44,
+	0x45, 0x53, 0x54, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x41, 0x4c, 0x4c, 0x4f, 0x57, 0x45, 0x44, 0x10,
+	0x04, 0x12, 0x18, 0x0a, 0x14, 0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f, 0x53, 0x45, 0x52, 0x56, 0x45,
+	0x52, 0x5f, 0x52, 0x45, 0x46, 0x55, 0x53, 0x45, 0x44, 0x10, 0x05, 0x12, 0x21, 0x0a, 0x1d, 0x43,
+	0x52, 0x4f, 0x53, 0x53, 0x5f, 0x53, 0x45, 0x52, 0x56, 0x45, 0x52, 0x5f, 0x48, 0x4f, 0x53, 0x54,
+	0x5f, 0x55, 0x4e, 0x52, 0x45, 0x41, 0x43, 0x48, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x06, 0x12, 0x20,
+	0x0a, 0x1c, 0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f, 0x53, 0x45, 0x52, 0x56, 0x45, 0x52, 0x5f, 0x4e,
+	0x45, 0x54, 0x5f, 0x55, 0x4e, 0x52, 0x45, 0x41, 0x43, 0x48, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x07,
+	0x12, 0x18, 0x0a, 0x14, 0x43, 0x52, 0x4f, 0x53, 0x53, 0x5f, 0x53, 0x45, 0x52, 0x56, 0x45, 0x52,
+	0x5f, 0x54, 0x49, 0x4d, 0x45, 0x4f, 0x55, 0x54, 0x10, 0x08, 0x12, 0x13, 0x0a, 0x0f, 0x43, 0x52,
+	0x4f, 0x53, 0x53, 0x5f, 0x4e, 0x4f, 0x5f, 0x57, 0x4f, 0x52, 0x4b, 0x45, 0x52, 0x10, 0x09, 0x32,
+	0x98, 0x03, 0x0a, 0x09, 0x52, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x41, 0x50, 0x49, 0x12, 0x29, 0x0a,
+	0x06, 0x53, 0x69, 0x67, 0x6e, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x69,
+	0x67, 0x6e, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x69,
+	0x67, 0x6e, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x12, 0x25, 0x0a, 0x07, 0x53, 0x69, 0x67, 0x6e,
+	0x6f, 0x66, 0x66, 0x12, 0x0e, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x52, 0x65, 0x71, 0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12,
+	0x42, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x43,
+	0x72, 0x6f, 0x73, 0x73, 0x12, 0x0e, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x65,
+	0x72, 0x52, 0x65, 0x71, 0x1a, 0x19, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x52, 0x65, 0x71, 0x22,
+	0x00, 0x30, 0x01, 0x12, 0x3e, 0x0a, 0x11, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x57, 0x6f, 0x72,
+	0x6b, 0x65, 0x72, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x12, 0x19, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x52,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x43, 0x72, 0x6f, 0x73, 0x73,
+	0x52, 0x65, 0x71, 0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22,
+	0x00, 0x28, 0x01, 0x12, 0x37, 0x0a, 0x09, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x12, 0x11, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x4d, 0x73, 0x67, 0x1a, 0x11, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x4d, 0x73, 0x67, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x12, 0x3c, 0x0a, 0x14,
+	0x4c, 0x69, 0x73, 0x74, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x18, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x3e, 0x0a, 0x13, 0x4b, 0x69,
+	0x6c, 0x6c, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x1b, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x4b, 0x69, 0x6c, 0x6c, 0x43, 0x72, 0x6f, 0x73,
+	0x73, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x1a, 0x0a,
+	0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x0c, 0x5a, 0x0a, 0x72, 0x70,
+	0x72, 0x6f, 0x78, 0x79, 0x2f, 0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -207,26 +855,43 @@ func file_api_proto_rawDescGZIP() []byte {
 	return file_api_proto_rawDescData
 }
 
-var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_api_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_api_proto_goTypes = []interface{}{
-	(*Empty)(nil),                // 0: api.Empty
-	(*CreateWorkerCrossReq)(nil), // 1: api.CreateWorkerCrossReq
-	(*ReportWorkerCrossReq)(nil), // 2: api.ReportWorkerCrossReq
+	(CrossStatus)(0),               // 0: api.CrossStatus
+	(*Empty)(nil),                  // 1: api.Empty
+	(*SignonReq)(nil),              // 2: api.SignonReq
+	(*SignonResp)(nil),             // 3: api.SignonResp
+	(*WorkerReq)(nil),              // 4: api.WorkerReq
+	(*CreateWorkerCrossReq)(nil),   // 5: api.CreateWorkerCrossReq
+	(*HeartbeatMsg)(nil),           // 6: api.HeartbeatMsg
+	(*CrossConnectionInfo)(nil),    // 7: api.CrossConnectionInfo
+	(*CrossConnectionList)(nil),    // 8: api.CrossConnectionList
+	(*KillCrossConnectionReq)(nil), // 9: api.KillCrossConnectionReq
+	(*ReportWorkerCrossReq)(nil),   // 10: api.ReportWorkerCrossReq
 }
 var file_api_proto_depIdxs = []int32{
-	0, // 0: api.RProxyAPI.Signon:input_type -> api.Empty
-	0, // 1: api.RProxyAPI.Signoff:input_type -> api.Empty
-	0, // 2: api.RProxyAPI.CreateWorkerCross:input_type -> api.Empty
-	2, // 3: api.RProxyAPI.ReportWorkerCross:input_type -> api.ReportWorkerCrossReq
-	0, // 4: api.RProxyAPI.Signon:output_type -> api.Empty
-	0, // 5: api.RProxyAPI.Signoff:output_type -> api.Empty
-	1, // 6: api.RProxyAPI.CreateWorkerCross:output_type -> api.CreateWorkerCrossReq
-	0, // 7: api.RProxyAPI.ReportWorkerCross:output_type -> api.Empty
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: api.CrossConnectionList.CrossConnections:type_name -> api.CrossConnectionInfo
+	0,  // 1: api.ReportWorkerCrossReq.Status:type_name -> api.CrossStatus
+	2,  // 2: api.RProxyAPI.Signon:input_type -> api.SignonReq
+	4,  // 3: api.RProxyAPI.Signoff:input_type -> api.WorkerReq
+	4,  // 4: api.RProxyAPI.CreateWorkerCross:input_type -> api.WorkerReq
+	10, // 5: api.RProxyAPI.ReportWorkerCross:input_type -> api.ReportWorkerCrossReq
+	6,  // 6: api.RProxyAPI.Heartbeat:input_type -> api.HeartbeatMsg
+	1,  // 7: api.RProxyAPI.ListCrossConnections:input_type -> api.Empty
+	9,  // 8: api.RProxyAPI.KillCrossConnection:input_type -> api.KillCrossConnectionReq
+	3,  // 9: api.RProxyAPI.Signon:output_type -> api.SignonResp
+	1,  // 10: api.RProxyAPI.Signoff:output_type -> api.Empty
+	5,  // 11: api.RProxyAPI.CreateWorkerCross:output_type -> api.CreateWorkerCrossReq
+	1,  // 12: api.RProxyAPI.ReportWorkerCross:output_type -> api.Empty
+	6,  // 13: api.RProxyAPI.Heartbeat:output_type -> api.HeartbeatMsg
+	8,  // 14: api.RProxyAPI.ListCrossConnections:output_type -> api.CrossConnectionList
+	1,  // 15: api.RProxyAPI.KillCrossConnection:output_type -> api.Empty
+	9,  // [9:16] is the sub-list for method output_type
+	2,  // [2:9] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_init() }
@@ -248,7 +913,7 @@ func file_api_proto_init() {
 			}
 		}
 		file_api_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateWorkerCrossReq); i {
+			switch v := v.(*SignonReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -260,6 +925,90 @@ func file_api_proto_init() {
 			}
 		}
 		file_api_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SignonResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WorkerReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateWorkerCrossReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HeartbeatMsg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CrossConnectionInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CrossConnectionList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KillCrossConnectionReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ReportWorkerCrossReq); i {
 			case 0:
 				return &v.state
@@ -277,13 +1026,14 @@ func file_api_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_api_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_goTypes,
 		DependencyIndexes: file_api_proto_depIdxs,
+		EnumInfos:         file_api_proto_enumTypes,
 		MessageInfos:      file_api_proto_msgTypes,
 	}.Build()
 	File_api_proto = out.File
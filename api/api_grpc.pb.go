@@ -17,10 +17,17 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RProxyAPIClient interface {
-	Signon(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
-	Signoff(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
-	CreateWorkerCross(ctx context.Context, in *Empty, opts ...grpc.CallOption) (RProxyAPI_CreateWorkerCrossClient, error)
+	Signon(ctx context.Context, in *SignonReq, opts ...grpc.CallOption) (*SignonResp, error)
+	Signoff(ctx context.Context, in *WorkerReq, opts ...grpc.CallOption) (*Empty, error)
+	CreateWorkerCross(ctx context.Context, in *WorkerReq, opts ...grpc.CallOption) (RProxyAPI_CreateWorkerCrossClient, error)
+	// worker ID and session ID are carried in metadata of the stream
 	ReportWorkerCross(ctx context.Context, opts ...grpc.CallOption) (RProxyAPI_ReportWorkerCrossClient, error)
+	// worker sends heartbeat periodically, reflector echoes it back;
+	// worker ID and session ID are carried in metadata of the stream
+	Heartbeat(ctx context.Context, opts ...grpc.CallOption) (RProxyAPI_HeartbeatClient, error)
+	// admin API, reflector's admin secret is carried in metadata
+	ListCrossConnections(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CrossConnectionList, error)
+	KillCrossConnection(ctx context.Context, in *KillCrossConnectionReq, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type rProxyAPIClient struct {
@@ -31,8 +38,8 @@ func NewRProxyAPIClient(cc grpc.ClientConnInterface) RProxyAPIClient {
 	return &rProxyAPIClient{cc}
 }
 
-func (c *rProxyAPIClient) Signon(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
+func (c *rProxyAPIClient) Signon(ctx context.Context, in *SignonReq, opts ...grpc.CallOption) (*SignonResp, error) {
+	out := new(SignonResp)
 	err := c.cc.Invoke(ctx, "/api.RProxyAPI/Signon", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -40,7 +47,7 @@ func (c *rProxyAPIClient) Signon(ctx context.Context, in *Empty, opts ...grpc.Ca
 	return out, nil
 }
 
-func (c *rProxyAPIClient) Signoff(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+func (c *rProxyAPIClient) Signoff(ctx context.Context, in *WorkerReq, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, "/api.RProxyAPI/Signoff", in, out, opts...)
 	if err != nil {
@@ -49,7 +56,7 @@ func (c *rProxyAPIClient) Signoff(ctx context.Context, in *Empty, opts ...grpc.C
 	return out, nil
 }
 
-func (c *rProxyAPIClient) CreateWorkerCross(ctx context.Context, in *Empty, opts ...grpc.CallOption) (RProxyAPI_CreateWorkerCrossClient, error) {
+func (c *rProxyAPIClient) CreateWorkerCross(ctx context.Context, in *WorkerReq, opts ...grpc.CallOption) (RProxyAPI_CreateWorkerCrossClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_RProxyAPI_serviceDesc.Streams[0], "/api.RProxyAPI/CreateWorkerCross", opts...)
 	if err != nil {
 		return nil, err
@@ -115,14 +122,70 @@ func (x *rProxyAPIReportWorkerCrossClient) CloseAndRecv() (*Empty, error) {
 	return m, nil
 }
 
+func (c *rProxyAPIClient) Heartbeat(ctx context.Context, opts ...grpc.CallOption) (RProxyAPI_HeartbeatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RProxyAPI_serviceDesc.Streams[2], "/api.RProxyAPI/Heartbeat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rProxyAPIHeartbeatClient{stream}
+	return x, nil
+}
+
+type RProxyAPI_HeartbeatClient interface {
+	Send(*HeartbeatMsg) error
+	Recv() (*HeartbeatMsg, error)
+	grpc.ClientStream
+}
+
+type rProxyAPIHeartbeatClient struct {
+	grpc.ClientStream
+}
+
+func (x *rProxyAPIHeartbeatClient) Send(m *HeartbeatMsg) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *rProxyAPIHeartbeatClient) Recv() (*HeartbeatMsg, error) {
+	m := new(HeartbeatMsg)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *rProxyAPIClient) ListCrossConnections(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CrossConnectionList, error) {
+	out := new(CrossConnectionList)
+	err := c.cc.Invoke(ctx, "/api.RProxyAPI/ListCrossConnections", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rProxyAPIClient) KillCrossConnection(ctx context.Context, in *KillCrossConnectionReq, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.RProxyAPI/KillCrossConnection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RProxyAPIServer is the server API for RProxyAPI service.
 // All implementations must embed UnimplementedRProxyAPIServer
 // for forward compatibility
 type RProxyAPIServer interface {
-	Signon(context.Context, *Empty) (*Empty, error)
-	Signoff(context.Context, *Empty) (*Empty, error)
-	CreateWorkerCross(*Empty, RProxyAPI_CreateWorkerCrossServer) error
+	Signon(context.Context, *SignonReq) (*SignonResp, error)
+	Signoff(context.Context, *WorkerReq) (*Empty, error)
+	CreateWorkerCross(*WorkerReq, RProxyAPI_CreateWorkerCrossServer) error
+	// worker ID and session ID are carried in metadata of the stream
 	ReportWorkerCross(RProxyAPI_ReportWorkerCrossServer) error
+	// worker sends heartbeat periodically, reflector echoes it back;
+	// worker ID and session ID are carried in metadata of the stream
+	Heartbeat(RProxyAPI_HeartbeatServer) error
+	// admin API, reflector's admin secret is carried in metadata
+	ListCrossConnections(context.Context, *Empty) (*CrossConnectionList, error)
+	KillCrossConnection(context.Context, *KillCrossConnectionReq) (*Empty, error)
 	mustEmbedUnimplementedRProxyAPIServer()
 }
 
@@ -130,18 +193,27 @@ type RProxyAPIServer interface {
 type UnimplementedRProxyAPIServer struct {
 }
 
-func (UnimplementedRProxyAPIServer) Signon(context.Context, *Empty) (*Empty, error) {
+func (UnimplementedRProxyAPIServer) Signon(context.Context, *SignonReq) (*SignonResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Signon not implemented")
 }
-func (UnimplementedRProxyAPIServer) Signoff(context.Context, *Empty) (*Empty, error) {
+func (UnimplementedRProxyAPIServer) Signoff(context.Context, *WorkerReq) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Signoff not implemented")
 }
-func (UnimplementedRProxyAPIServer) CreateWorkerCross(*Empty, RProxyAPI_CreateWorkerCrossServer) error {
+func (UnimplementedRProxyAPIServer) CreateWorkerCross(*WorkerReq, RProxyAPI_CreateWorkerCrossServer) error {
 	return status.Errorf(codes.Unimplemented, "method CreateWorkerCross not implemented")
 }
 func (UnimplementedRProxyAPIServer) ReportWorkerCross(RProxyAPI_ReportWorkerCrossServer) error {
 	return status.Errorf(codes.Unimplemented, "method ReportWorkerCross not implemented")
 }
+func (UnimplementedRProxyAPIServer) Heartbeat(RProxyAPI_HeartbeatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedRProxyAPIServer) ListCrossConnections(context.Context, *Empty) (*CrossConnectionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCrossConnections not implemented")
+}
+func (UnimplementedRProxyAPIServer) KillCrossConnection(context.Context, *KillCrossConnectionReq) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KillCrossConnection not implemented")
+}
 func (UnimplementedRProxyAPIServer) mustEmbedUnimplementedRProxyAPIServer() {}
 
 // UnsafeRProxyAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -156,7 +228,7 @@ func RegisterRProxyAPIServer(s grpc.ServiceRegistrar, srv RProxyAPIServer) {
 }
 
 func _RProxyAPI_Signon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(SignonReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -168,13 +240,13 @@ func _RProxyAPI_Signon_Handler(srv interface{}, ctx context.Context, dec func(in
 		FullMethod: "/api.RProxyAPI/Signon",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RProxyAPIServer).Signon(ctx, req.(*Empty))
+		return srv.(RProxyAPIServer).Signon(ctx, req.(*SignonReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
 func _RProxyAPI_Signoff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(WorkerReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -186,13 +258,13 @@ func _RProxyAPI_Signoff_Handler(srv interface{}, ctx context.Context, dec func(i
 		FullMethod: "/api.RProxyAPI/Signoff",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RProxyAPIServer).Signoff(ctx, req.(*Empty))
+		return srv.(RProxyAPIServer).Signoff(ctx, req.(*WorkerReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
 func _RProxyAPI_CreateWorkerCross_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(Empty)
+	m := new(WorkerReq)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -238,6 +310,68 @@ func (x *rProxyAPIReportWorkerCrossServer) Recv() (*ReportWorkerCrossReq, error)
 	return m, nil
 }
 
+func _RProxyAPI_Heartbeat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RProxyAPIServer).Heartbeat(&rProxyAPIHeartbeatServer{stream})
+}
+
+type RProxyAPI_HeartbeatServer interface {
+	Send(*HeartbeatMsg) error
+	Recv() (*HeartbeatMsg, error)
+	grpc.ServerStream
+}
+
+type rProxyAPIHeartbeatServer struct {
+	grpc.ServerStream
+}
+
+func (x *rProxyAPIHeartbeatServer) Send(m *HeartbeatMsg) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *rProxyAPIHeartbeatServer) Recv() (*HeartbeatMsg, error) {
+	m := new(HeartbeatMsg)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _RProxyAPI_ListCrossConnections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RProxyAPIServer).ListCrossConnections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.RProxyAPI/ListCrossConnections",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RProxyAPIServer).ListCrossConnections(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RProxyAPI_KillCrossConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KillCrossConnectionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RProxyAPIServer).KillCrossConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.RProxyAPI/KillCrossConnection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RProxyAPIServer).KillCrossConnection(ctx, req.(*KillCrossConnectionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _RProxyAPI_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.RProxyAPI",
 	HandlerType: (*RProxyAPIServer)(nil),
@@ -250,6 +384,14 @@ var _RProxyAPI_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Signoff",
 			Handler:    _RProxyAPI_Signoff_Handler,
 		},
+		{
+			MethodName: "ListCrossConnections",
+			Handler:    _RProxyAPI_ListCrossConnections_Handler,
+		},
+		{
+			MethodName: "KillCrossConnection",
+			Handler:    _RProxyAPI_KillCrossConnection_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -262,6 +404,12 @@ var _RProxyAPI_serviceDesc = grpc.ServiceDesc{
 			Handler:       _RProxyAPI_ReportWorkerCross_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Heartbeat",
+			Handler:       _RProxyAPI_Heartbeat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "api.proto",
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metadata keys carrying worker identity on RPC without a WorkerReq
+const (
+	mdWorkerID    = "rproxy-worker-id"
+	mdSessionID   = "rproxy-session-id"
+	mdAdminSecret = "rproxy-admin-secret" //carried by admin RPC
+)
+
+func newSessionID() string {
+	return hex.EncodeToString(newToken())
+}
+
+// checkSecret returns true if secret matches expected, any secret matches if expected is empty
+func checkSecret(expected, secret string) bool {
+	if expected == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1
+}
+
+// authWorker returns the signed on worker workerID if sessionID is its current session,
+// otherwise a gRPC Unauthenticated error
+func (refl *Reflector) authWorker(workerID, sessionID string) (*RemoteWorker, error) {
+	refl.workerLock.RLock()
+	defer refl.workerLock.RUnlock()
+	w, ok := refl.Workers[workerID]
+	if !ok || subtle.ConstantTimeCompare([]byte(w.SessionID), []byte(sessionID)) != 1 {
+		log.Printf("rejected request of worker %v with invalid session", workerID)
+		return nil, status.Errorf(codes.Unauthenticated, "worker %v is not signed on or session is invalid", workerID)
+	}
+	return w, nil
+}
+
+// authWorkerStream is authWorker using worker identity in metadata of ctx
+func (refl *Reflector) authWorkerStream(ctx context.Context) (*RemoteWorker, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var workerID, sessionID string
+	if v := md.Get(mdWorkerID); len(v) > 0 {
+		workerID = v[0]
+	}
+	if v := md.Get(mdSessionID); len(v) > 0 {
+		sessionID = v[0]
+	}
+	return refl.authWorker(workerID, sessionID)
+}
+
+// authAdmin returns a gRPC Unauthenticated error if the admin secret in metadata of ctx doesn't match reflector's,
+// admin API is disabled if reflector has no admin secret
+func (refl *Reflector) authAdmin(ctx context.Context) error {
+	if refl.adminSecret == "" {
+		return status.Error(codes.PermissionDenied, "admin API is disabled, no admin secret configured on reflector")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var secret string
+	if v := md.Get(mdAdminSecret); len(v) > 0 {
+		secret = v[0]
+	}
+	if subtle.ConstantTimeCompare([]byte(refl.adminSecret), []byte(secret)) != 1 {
+		p, _ := peer.FromContext(ctx)
+		log.Printf("rejected admin request from %v with invalid admin secret", p.Addr)
+		return status.Error(codes.Unauthenticated, "invalid admin secret")
+	}
+	return nil
+}
+
+// workerStreamContext returns ctx carrying worker identity in metadata, for RPC without a WorkerReq
+func (w *Worker) workerStreamContext(ctx context.Context) context.Context {
+	sessionID, _ := w.session()
+	return metadata.AppendToOutgoingContext(ctx, mdWorkerID, w.ID, mdSessionID, sessionID)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of both roles, loaded from the config file and command line flags;
+// yaml keys are the same as flag names
+type Config struct {
+	Role         string        `yaml:"role"`
+	ClientPort   uint          `yaml:"clport"`
+	WorkerPort   uint          `yaml:"wlport"`
+	APIPort      uint          `yaml:"apiport"`
+	Refl         string        `yaml:"refl"`
+	ReflAPI      string        `yaml:"reflapi"`
+	Server       string        `yaml:"svr"`
+	ID           string        `yaml:"id"`
+	Mux          uint          `yaml:"mux"`
+	Pool         uint          `yaml:"pool"`
+	ProxyPort    uint          `yaml:"proxyport"`
+	LocalProxy   bool          `yaml:"localproxy"`
+	Profiling    bool          `yaml:"p"`
+	Metrics      string        `yaml:"metrics"`
+	HBInterval   time.Duration `yaml:"hbinterval"`
+	HBMiss       uint          `yaml:"hbmiss"`
+	IdleTimeout  time.Duration `yaml:"idletimeout"`
+	MaxLifetime  time.Duration `yaml:"maxlifetime"`
+	DrainTimeout time.Duration `yaml:"draintimeout"`
+	PairTimeout  time.Duration `yaml:"pairtimeout"`
+	Secret       string        `yaml:"secret"`
+	AdminSecret  string        `yaml:"adminsecret"`
+	TLSCert      string        `yaml:"tlscert"`
+	TLSKey       string        `yaml:"tlskey"`
+	TLSCA        string        `yaml:"tlsca"`
+	TLSName      string        `yaml:"tlsname"`
+	Tunnels      tunnelList    `yaml:"tunnels"`
+	Targets      targetMap     `yaml:"targets"`
+	AllowDest    destList      `yaml:"allowdest"`
+	AllowAnyDest bool          `yaml:"allowanydest"`
+	ACL          `yaml:",inline"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Role:         workerRole,
+		ClientPort:   defaultToClientListenPort,
+		WorkerPort:   defaultToWOrkerListenPort,
+		APIPort:      defaultReflAPIListenPort,
+		ProxyPort:    defaultProxyPort,
+		LocalProxy:   true,
+		HBInterval:   defaultHeartbeatInterval,
+		HBMiss:       defaultHeartbeatMiss,
+		DrainTimeout: defaultDrainTimeout,
+		PairTimeout:  defaultPairTimeout,
+		Targets:      make(targetMap),
+	}
+}
+
+// bindFlags defines flags in fs setting fields of c, current values of c are the defaults
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.UintVar(&c.ClientPort, "clport", c.ClientPort, "http client facing listen port of default tunnel, 0 means no default tunnel")
+	fs.UintVar(&c.WorkerPort, "wlport", c.WorkerPort, "worker facing listen port")
+	fs.UintVar(&c.APIPort, "apiport", c.APIPort, "reflector API listen port")
+	fs.StringVar(&c.Role, "role", c.Role, "role")
+	fs.StringVar(&c.Refl, "refl", c.Refl, "reflector tcp address")
+	fs.StringVar(&c.ReflAPI, "reflapi", c.ReflAPI, "reflector api tcp address")
+	fs.StringVar(&c.Server, "svr", c.Server, "server tcp address")
+	fs.StringVar(&c.ID, "id", c.ID, "worker ID, default is hostname")
+	fs.UintVar(&c.Mux, "mux", c.Mux, "number of multiplexed data connections worker keeps to reflector, 0 means a new data connection for each cross connection")
+	fs.UintVar(&c.Pool, "pool", c.Pool, "number of idle pre-dialed data connections worker keeps to reflector, ignored if mux is used")
+	fs.UintVar(&c.ProxyPort, "proxyport", c.ProxyPort, "http proxy listen port")
+	fs.BoolVar(&c.LocalProxy, "localproxy", c.LocalProxy, "use local http proxy")
+	fs.BoolVar(&c.Profiling, "p", c.Profiling, "enable profiling")
+	fs.StringVar(&c.Metrics, "metrics", c.Metrics, "listen address of prometheus metrics endpoint, empty means disabled")
+	fs.DurationVar(&c.HBInterval, "hbinterval", c.HBInterval, "reflector only, heartbeat interval of workers, 0 disables heartbeat")
+	fs.DurationVar(&c.IdleTimeout, "idletimeout", c.IdleTimeout, "cross connection without data in either direction for it is closed, 0 means no timeout")
+	fs.DurationVar(&c.MaxLifetime, "maxlifetime", c.MaxLifetime, "cross connection is closed after running for it, 0 means no limit")
+	fs.DurationVar(&c.DrainTimeout, "draintimeout", c.DrainTimeout, "on SIGINT or SIGTERM, time to wait for active cross connections to finish before closing them")
+	fs.DurationVar(&c.PairTimeout, "pairtimeout", c.PairTimeout, "reflector only, client connection not paired with worker data connection within it is closed, 0 means no timeout")
+	fs.UintVar(&c.HBMiss, "hbmiss", c.HBMiss, "reflector only, number of missed heartbeats before a worker or reflector is considered dead")
+	fs.StringVar(&c.Secret, "secret", c.Secret, "pre-shared secret, worker presents it to sign on, reflector requires it if specified")
+	fs.StringVar(&c.AdminSecret, "adminsecret", c.AdminSecret, "reflector only, secret required by admin API used by ctl, must differ from secret, admin API is disabled if empty")
+	fs.StringVar(&c.TLSCert, "tlscert", c.TLSCert, "TLS certificate file, enables TLS for API and data connections")
+	fs.StringVar(&c.TLSKey, "tlskey", c.TLSKey, "TLS private key file")
+	fs.StringVar(&c.TLSCA, "tlsca", c.TLSCA, "TLS CA certificate file, used to verify peer certificate")
+	fs.StringVar(&c.TLSName, "tlsname", c.TLSName, "worker only, reflector name in its TLS certificate, default is host of reflector address")
+	fs.Var(&c.Tunnels, "tunnel", "reflector tunnel in format of name=listenaddr[,target[,mode]], mode is tcp (default), http, socks5, connect or udp, could be specified multiple times")
+	fs.Var(&c.Allow, "allow", "reflector only, CIDR or IP address of allowed clients, could be specified multiple times, default is allowing all")
+	fs.Var(&c.Deny, "deny", "reflector only, CIDR or IP address of denied clients, could be specified multiple times, takes precedence over allow")
+	fs.Var(&c.AllowDest, "allowdest", "worker only, destination reflector could ask worker to connect to, in format of host:port, CIDR[:port] or IP address, could be specified multiple times, default is allowing none, only worker's own target and svr are used")
+	fs.BoolVar(&c.AllowAnyDest, "allowanydest", c.AllowAnyDest, "worker only, allow any destination from reflector, including those requested by clients of socks5 and connect tunnels, when allowdest is empty, making worker an open proxy")
+	fs.Var(c.Targets, "target", "worker server address of a tunnel in format of name=server, could be specified multiple times")
+}
+
+// loadConfig parses command line args, if a config file is specified with -config, it is loaded first and
+// flags in args override values in it; -allow, -deny and -allowdest replace the lists in the file rather than
+// adding to them
+func loadConfig(args []string) (*Config, error) {
+	c := defaultConfig()
+	fs := newConfigFlagSet(c)
+	fs.Parse(args)
+	path := fs.Lookup("config").Value.String()
+	if path != "" {
+		c = defaultConfig()
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "allow":
+				c.Allow = nil
+			case "deny":
+				c.Deny = nil
+			case "allowdest":
+				c.AllowDest = nil
+			}
+		})
+		newConfigFlagSet(c).Parse(args)
+	}
+	if err := c.validate(); err != nil {
+		if path != "" {
+			return nil, fmt.Errorf("invalid config %v, %w", path, err)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func newConfigFlagSet(c *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.String("config", "", "YAML config file, flags override values in it")
+	c.bindFlags(fs)
+	return fs
+}
+
+// loadFile loads YAML config file path into c, unknown keys are errors
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file, %w", err)
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err = dec.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %v, %w", path, err)
+	}
+	if c.Targets == nil {
+		c.Targets = make(targetMap)
+	}
+	return nil
+}
+
+// reflTunnels returns tunnels of reflector, including the default tunnel if clport is not 0
+func (c *Config) reflTunnels() []*Tunnel {
+	tunnels := append([]*Tunnel{}, c.Tunnels...)
+	if c.ClientPort != 0 {
+		tunnels = append(tunnels, &Tunnel{
+			Name:       defaultTunnelName,
+			ListenAddr: fmt.Sprintf("0.0.0.0:%d", c.ClientPort),
+			Mode:       tunnelModeHTTP,
+		})
+	}
+	return tunnels
+}
+
+// validate checks c, returned error names the offending key
+func (c *Config) validate() error {
+	switch c.Role {
+	case reflRole, workerRole:
+	default:
+		return fmt.Errorf("role: invalid role %v, expect %v or %v", c.Role, reflRole, workerRole)
+	}
+	for key, port := range map[string]uint{
+		"clport":    c.ClientPort,
+		"wlport":    c.WorkerPort,
+		"apiport":   c.APIPort,
+		"proxyport": c.ProxyPort,
+	} {
+		if port > 65535 {
+			return fmt.Errorf("%v: invalid port %d", key, port)
+		}
+	}
+	for key, d := range map[string]time.Duration{
+		"hbinterval":   c.HBInterval,
+		"idletimeout":  c.IdleTimeout,
+		"maxlifetime":  c.MaxLifetime,
+		"draintimeout": c.DrainTimeout,
+		"pairtimeout":  c.PairTimeout,
+	} {
+		if d < 0 {
+			return fmt.Errorf("%v: negative duration %v", key, d)
+		}
+	}
+	if c.AdminSecret != "" && c.AdminSecret == c.Secret {
+		return fmt.Errorf("adminsecret: must differ from secret shared with workers")
+	}
+	if c.HBInterval > 0 && c.HBMiss == 0 {
+		return fmt.Errorf("hbmiss: must be greater than 0 when heartbeat is enabled")
+	}
+	if c.Metrics != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics); err != nil {
+			return fmt.Errorf("metrics: invalid address %v, %w", c.Metrics, err)
+		}
+	}
+	names := make(map[string]bool)
+	for i, t := range c.Tunnels {
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("tunnels[%d].%w", i, err)
+		}
+		if names[t.Name] || (t.Name == defaultTunnelName && c.ClientPort != 0) {
+			return fmt.Errorf("tunnels[%d].name: duplicate tunnel name %v", i, t.Name)
+		}
+		names[t.Name] = true
+	}
+	for name, target := range c.Targets {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("targets.%v: invalid server address %v, %w", name, target, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFlagsOverrideFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rproxy.yaml")
+	file := `role: refl
+secret: filesecret
+allow: [10.0.0.0/8]
+deny: [10.1.0.0/16]
+allowdest: [10.0.0.0/8:22]
+targets:
+  db: 10.0.0.9:5432
+  web: 10.0.0.6:80
+`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name                   string
+		args                   []string
+		allow, deny, allowdest string
+		secret, db             string
+	}{
+		{
+			name:      "file only",
+			args:      []string{"-config", path},
+			allow:     "10.0.0.0/8",
+			deny:      "10.1.0.0/16",
+			allowdest: "10.0.0.0/8:22",
+			secret:    "filesecret",
+			db:        "10.0.0.9:5432",
+		},
+		{
+			name:      "lists replaced",
+			args:      []string{"-config", path, "-allow", "192.168.0.0/16", "-allow", "172.16.0.1", "-deny", "192.168.1.0/24", "-allowdest", "db.internal:5432"},
+			allow:     "192.168.0.0/16,172.16.0.1/32",
+			deny:      "192.168.1.0/24",
+			allowdest: "db.internal:5432",
+			secret:    "filesecret",
+			db:        "10.0.0.9:5432",
+		},
+		{
+			name:      "only flagged list replaced",
+			args:      []string{"-allow", "192.168.0.0/16", "-config", path},
+			allow:     "192.168.0.0/16",
+			deny:      "10.1.0.0/16",
+			allowdest: "10.0.0.0/8:22",
+			secret:    "filesecret",
+			db:        "10.0.0.9:5432",
+		},
+		{
+			name:      "scalar and target overridden",
+			args:      []string{"-config", path, "-secret", "flagsecret", "-target", "db=10.0.0.10:5432"},
+			allow:     "10.0.0.0/8",
+			deny:      "10.1.0.0/16",
+			allowdest: "10.0.0.0/8:22",
+			secret:    "flagsecret",
+			db:        "10.0.0.10:5432",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, err := loadConfig(c.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Allow.String(); got != c.allow {
+				t.Errorf("allow is %v, expect %v", got, c.allow)
+			}
+			if got := cfg.Deny.String(); got != c.deny {
+				t.Errorf("deny is %v, expect %v", got, c.deny)
+			}
+			if got := cfg.AllowDest.String(); got != c.allowdest {
+				t.Errorf("allowdest is %v, expect %v", got, c.allowdest)
+			}
+			if cfg.Secret != c.secret {
+				t.Errorf("secret is %v, expect %v", cfg.Secret, c.secret)
+			}
+			if cfg.Targets["db"] != c.db || cfg.Targets["web"] != "10.0.0.6:80" {
+				t.Errorf("targets are %v, expect db=%v and web from file", cfg.Targets, c.db)
+			}
+		})
+	}
+}
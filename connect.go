@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"rproxy/api"
+	"strings"
+)
+
+const maxConnectHeaderSize = 8192
+
+// connectHandshake reads HTTP CONNECT request from client of conn, if user is not empty, client must authenticate
+// with user and pass via Proxy-Authorization; it returns the requested destination, the response to a valid request
+// is not sent
+func connectHandshake(conn net.Conn, user, pass string) (string, error) {
+	hdr, err := readHTTPHeader(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CONNECT request, %w", err)
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(hdr)))
+	if err != nil {
+		writeHTTPResponse(conn, http.StatusBadRequest, "", "")
+		return "", fmt.Errorf("invalid CONNECT request, %w", err)
+	}
+	if req.Method != http.MethodConnect {
+		writeHTTPResponse(conn, http.StatusMethodNotAllowed, "Allow: CONNECT\r\n", "")
+		return "", fmt.Errorf("unsupported method %v", req.Method)
+	}
+	if user != "" && !checkProxyAuth(req.Header.Get("Proxy-Authorization"), user, pass) {
+		writeHTTPResponse(conn, http.StatusProxyAuthRequired, "Proxy-Authenticate: Basic realm=\"rproxy\"\r\n", "")
+		return "", fmt.Errorf("invalid proxy authorization of CONNECT %v", req.RequestURI)
+	}
+	if _, _, err := net.SplitHostPort(req.RequestURI); err != nil {
+		writeHTTPResponse(conn, http.StatusBadRequest, "", "")
+		return "", fmt.Errorf("invalid CONNECT destination %v, %w", req.RequestURI, err)
+	}
+	return req.RequestURI, nil
+}
+
+// readHTTPHeader reads from conn until the end of HTTP header, one byte at a time so that nothing sent by client
+// after the header is consumed
+func readHTTPHeader(conn net.Conn) ([]byte, error) {
+	hdr := make([]byte, 0, 512)
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(hdr, []byte("\r\n\r\n")) && !bytes.HasSuffix(hdr, []byte("\n\n")) {
+		if len(hdr) >= maxConnectHeaderSize {
+			return nil, fmt.Errorf("header exceeds %d bytes", maxConnectHeaderSize)
+		}
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return nil, err
+		}
+		hdr = append(hdr, b[0])
+	}
+	return hdr, nil
+}
+
+// checkProxyAuth returns true if Proxy-Authorization header auth is basic authentication of user and pass
+func checkProxyAuth(auth, user, pass string) bool {
+	const prefix = "basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	cred, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return false
+	}
+	fields := strings.SplitN(string(cred), ":", 2)
+	if len(fields) != 2 {
+		return false
+	}
+	u, p := fields[0], fields[1]
+	return subtle.ConstantTimeCompare([]byte(u), []byte(user))&subtle.ConstantTimeCompare([]byte(p), []byte(pass)) == 1
+}
+
+// writeHTTPResponse writes a response with status code, extra header lines and text body to conn
+func writeHTTPResponse(conn net.Conn, code int, header, body string) error {
+	_, err := fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\n%sContent-Type: text/plain\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+		code, http.StatusText(code), header, len(body), body)
+	return err
+}
+
+// httpFailureCode returns HTTP status code of failure status st reported by worker
+func httpFailureCode(st api.CrossStatus) int {
+	if st == api.CrossStatus_CROSS_SERVER_TIMEOUT {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
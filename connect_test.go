@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestConnectHandshake(t *testing.T) {
+	basic := func(cred string) string {
+		return "Proxy-Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(cred)) + "\r\n"
+	}
+	cases := []struct {
+		name       string
+		user, pass string
+		input      string
+		target     string //empty means handshake fails
+		status     string //status line of the reply, empty means no reply
+	}{
+		{
+			name:   "connect",
+			input:  "CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n",
+			target: "example.com:443",
+		},
+		{
+			name:   "ipv6 destination",
+			input:  "CONNECT [2001:db8::1]:22 HTTP/1.1\r\n\r\n",
+			target: "[2001:db8::1]:22",
+		},
+		{
+			name:   "valid credentials",
+			user:   "u",
+			pass:   "p:w",
+			input:  "CONNECT example.com:443 HTTP/1.1\r\n" + basic("u:p:w") + "\r\n",
+			target: "example.com:443",
+		},
+		{
+			name:   "wrong password",
+			user:   "u",
+			pass:   "p",
+			input:  "CONNECT example.com:443 HTTP/1.1\r\n" + basic("u:x") + "\r\n",
+			status: "HTTP/1.1 407 Proxy Authentication Required",
+		},
+		{
+			name:   "malformed authorization",
+			user:   "u",
+			pass:   "p",
+			input:  "CONNECT example.com:443 HTTP/1.1\r\nProxy-Authorization: Basic !!!\r\n\r\n",
+			status: "HTTP/1.1 407 Proxy Authentication Required",
+		},
+		{
+			name:   "non basic authorization",
+			user:   "u",
+			pass:   "p",
+			input:  "CONNECT example.com:443 HTTP/1.1\r\nProxy-Authorization: Bearer dTpw\r\n\r\n",
+			status: "HTTP/1.1 407 Proxy Authentication Required",
+		},
+		{
+			name:   "missing authorization",
+			user:   "u",
+			pass:   "p",
+			input:  "CONNECT example.com:443 HTTP/1.1\r\n\r\n",
+			status: "HTTP/1.1 407 Proxy Authentication Required",
+		},
+		{
+			name:   "other method",
+			input:  "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			status: "HTTP/1.1 405 Method Not Allowed",
+		},
+		{
+			name:   "destination without port",
+			input:  "CONNECT example.com HTTP/1.1\r\n\r\n",
+			status: "HTTP/1.1 400 Bad Request",
+		},
+		{
+			name:   "malformed request",
+			input:  "CONNECT\r\n\r\n",
+			status: "HTTP/1.1 400 Bad Request",
+		},
+		{
+			name:  "oversized header",
+			input: "CONNECT example.com:443 HTTP/1.1\r\nX: " + strings.Repeat("a", maxConnectHeaderSize) + "\r\n\r\n",
+		},
+		{
+			name:  "truncated header",
+			input: "CONNECT example.com:443 HTTP/1.1\r\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			target, err, reply := runHandshake([]byte(c.input), func(conn net.Conn) (string, error) {
+				return connectHandshake(conn, c.user, c.pass)
+			})
+			if c.target == "" && err == nil {
+				t.Errorf("handshake succeeded with target %v, expect failure", target)
+			}
+			if c.target != "" && (err != nil || target != c.target) {
+				t.Errorf("got target %q and error %v, expect %q", target, err, c.target)
+			}
+			status := strings.SplitN(string(reply), "\r\n", 2)[0]
+			if status != c.status {
+				t.Errorf("got reply %q, expect %q", status, c.status)
+			}
+		})
+	}
+}
+
+func TestConnectHandshakeKeepsData(t *testing.T) {
+	srv, cli := net.Pipe()
+	defer cli.Close()
+	defer srv.Close()
+	go cli.Write([]byte("CONNECT example.com:443 HTTP/1.1\r\nContent-Length: 0\r\n\r\nearly data"))
+	target, err := connectHandshake(srv, "", "")
+	if err != nil || target != "example.com:443" {
+		t.Fatalf("got target %q and error %v", target, err)
+	}
+	b := make([]byte, len("early data"))
+	if _, err = io.ReadFull(srv, b); err != nil || string(b) != "early data" {
+		t.Errorf("got data after header %q and error %v, expect %q", b, err, "early data")
+	}
+}
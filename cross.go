@@ -1,22 +1,184 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	copyBufSize = 32 * 1024
 )
 
 type CrossConnection struct {
+	//accessed atomically, keep them first for 64-bit alignment
+	bytes1to2    uint64 //bytes copied so far from Conn1 to Conn2
+	bytes2to1    uint64 //bytes copied so far from Conn2 to Conn1
+	lastActivity int64  //unix time in nanoseconds of last data copied in either direction
+	reasonSet    int32  //set to 1 when closeReason is recorded
+	closeReason  string //why cc ended, only the first one is recorded
 	ID           int
-	Conn1, Conn2 *net.TCPConn
+	WorkerID     string //only used by reflector
+	Tunnel       string //only used by reflector
+	Mode         string //only used by reflector, mode of the tunnel, decides how client is answered
+	waitReport   bool   //only used by reflector, cc is started on worker's report since client must be answered first
+	Conn1, Conn2 net.Conn
+	StartTime    time.Time
+	IdleTimeout  time.Duration //cc is closed if no data is copied in either direction for it, 0 means no timeout
+	MaxLifetime  time.Duration //cc is closed when it has run for it, 0 means no limit
 }
 
+var (
+	errIdleTimeout = errors.New("idle timeout")
+	errMaxLifetime = errors.New("max lifetime reached")
+)
+
 func (cc CrossConnection) String() string {
+	if cc.Conn2 == nil {
+		return fmt.Sprintf("crossconnection %d of %v", cc.ID, cc.Conn1.RemoteAddr())
+	}
 	return fmt.Sprintf("crossconnection %d between %v and %v", cc.ID, cc.Conn1.RemoteAddr(), cc.Conn2.RemoteAddr())
 }
-func (cc *CrossConnection) Complete(c2 *net.TCPConn) error {
+
+// CrossConnectionStats is a snapshot of the counters of a cross connection
+type CrossConnectionStats struct {
+	StartTime    time.Time
+	LastActivity time.Time //zero if no data has been copied
+	BytesIn      uint64    //bytes copied from Conn1 to Conn2
+	BytesOut     uint64    //bytes copied from Conn2 to Conn1
+}
+
+// Duration returns time elapsed since start of the cross connection
+func (st CrossConnectionStats) Duration() time.Duration {
+	return time.Since(st.StartTime)
+}
+
+// Stats returns current counters of cc, safe to call while cc is running
+func (cc *CrossConnection) Stats() CrossConnectionStats {
+	st := CrossConnectionStats{
+		StartTime: cc.StartTime,
+		BytesIn:   atomic.LoadUint64(&cc.bytes1to2),
+		BytesOut:  atomic.LoadUint64(&cc.bytes2to1),
+	}
+	if last := atomic.LoadInt64(&cc.lastActivity); last != 0 {
+		st.LastActivity = time.Unix(0, last)
+	}
+	return st
+}
+
+// setCloseReason records why cc ends if it is not recorded yet
+func (cc *CrossConnection) setCloseReason(reason string) {
+	if atomic.CompareAndSwapInt32(&cc.reasonSet, 0, 1) {
+		cc.closeReason = reason
+	}
+}
+
+// deadline returns the time cc expires if no more data is copied, zero if there is neither idle timeout nor max lifetime
+func (cc *CrossConnection) deadline() time.Time {
+	var dl time.Time
+	if cc.IdleTimeout > 0 {
+		last := cc.StartTime
+		if t := atomic.LoadInt64(&cc.lastActivity); t != 0 {
+			last = time.Unix(0, t)
+		}
+		dl = last.Add(cc.IdleTimeout)
+	}
+	if cc.MaxLifetime > 0 {
+		if end := cc.StartTime.Add(cc.MaxLifetime); dl.IsZero() || end.Before(dl) {
+			dl = end
+		}
+	}
+	return dl
+}
+
+// expired returns errMaxLifetime or errIdleTimeout if cc has expired, nil otherwise
+func (cc *CrossConnection) expired() error {
+	if cc.MaxLifetime > 0 && time.Since(cc.StartTime) >= cc.MaxLifetime {
+		return errMaxLifetime
+	}
+	if dl := cc.deadline(); cc.IdleTimeout > 0 && !time.Now().Before(dl) {
+		return errIdleTimeout
+	}
+	return nil
+}
+
+// copy copies from src to dst until EOF or error, counter, metric and last activity time are updated after each write;
+// if cc has idle timeout or max lifetime, it is enforced with deadlines of src and dst, and errIdleTimeout or
+// errMaxLifetime is returned when cc expires
+func (cc *CrossConnection) copy(dst, src net.Conn, counter *uint64, metric prometheus.Counter) (int64, error) {
+	buf := make([]byte, copyBufSize)
+	var written int64
+	for {
+		dl := cc.deadline()
+		if !dl.IsZero() {
+			src.SetReadDeadline(dl)
+		}
+		nr, rerr := src.Read(buf)
+		if nr > 0 {
+			if !dl.IsZero() {
+				dst.SetWriteDeadline(cc.deadline())
+			}
+			nw, werr := dst.Write(buf[:nr])
+			if nw > 0 {
+				written += int64(nw)
+				atomic.AddUint64(counter, uint64(nw))
+				metric.Add(float64(nw))
+				atomic.StoreInt64(&cc.lastActivity, time.Now().UnixNano())
+			}
+			if werr != nil {
+				if err := cc.expired(); err != nil && isTimeout(werr) {
+					return written, err
+				}
+				return written, werr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			if isTimeout(rerr) && !dl.IsZero() {
+				if err := cc.expired(); err != nil {
+					return written, err
+				}
+				//the other direction is active
+				continue
+			}
+			return written, rerr
+		}
+	}
+}
+
+// isTimeout returns true if err is a timeout caused by deadline
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// endDirection records close reason of cc when direction from src ends with err
+func (cc *CrossConnection) endDirection(src net.Conn, err error) {
+	if err == nil {
+		cc.setCloseReason(fmt.Sprintf("closed by %v", src.RemoteAddr()))
+		return
+	}
+	cc.setCloseReason(err.Error())
+}
+
+// pending returns true if cc is not started yet on reflector
+func (cc *CrossConnection) pending() bool {
+	return cc.Conn2 == nil || cc.waitReport
+}
+
+func (cc *CrossConnection) Complete(c2 net.Conn) error {
 	if cc.Conn2 != nil {
 		return fmt.Errorf("%v is already completed", cc)
 	}
@@ -30,25 +192,57 @@ func (cc *CrossConnection) Run() {
 		return
 	}
 	log.Printf("%v started", cc.String())
-	defer log.Printf("%v ended", cc.String())
+	activeCrossConnections.Inc()
+	defer activeCrossConnections.Dec()
+	defer func() {
+		st := cc.Stats()
+		log.Printf("%v ended, %v, lasted %v, %d bytes in, %d bytes out", cc.String(), cc.closeReason,
+			st.Duration().Round(time.Millisecond), st.BytesIn, st.BytesOut)
+	}()
+	//a stream over multiplexed data connection closed by the peer is aborted, which ends cc
+	for _, c := range []net.Conn{cc.Conn1, cc.Conn2} {
+		if s, ok := c.(*muxStream); ok {
+			s.setAbortHandler(func() {
+				cc.setCloseReason("aborted by peer")
+				cc.Conn1.Close()
+				cc.Conn2.Close()
+			})
+		}
+	}
 	wg2 := new(sync.WaitGroup)
 	wg2.Add(2)
 	go func() {
-		n, err := io.Copy(cc.Conn1, cc.Conn2)
-		log.Printf("%v-> %v ended, %d bytes copied, err is %v",
-			cc.Conn2.RemoteAddr(), cc.Conn1.RemoteAddr(), n, err)
-		cc.Conn1.Close()
-		cc.Conn2.Close()
+		cc.runDirection(cc.Conn1, cc.Conn2, &cc.bytes2to1, bytesOut)
 		wg2.Done()
-
 	}()
 	go func() {
-		n, err := io.Copy(cc.Conn2, cc.Conn1)
-		log.Printf("%v-> %v ended, %d bytes copied, err is %v",
-			cc.Conn1.RemoteAddr(), cc.Conn2.RemoteAddr(), n, err)
-		cc.Conn1.Close()
-		cc.Conn2.Close()
+		cc.runDirection(cc.Conn2, cc.Conn1, &cc.bytes1to2, bytesIn)
 		wg2.Done()
 	}()
 	wg2.Wait()
+	cc.setCloseReason("closed by both sides")
+	cc.Conn1.Close()
+	cc.Conn2.Close()
+}
+
+// runDirection copies from src to dst; when src reaches EOF, the half-close is propagated to dst so that
+// the other direction keeps going, both connections are closed on error or if dst doesn't support half-close
+func (cc *CrossConnection) runDirection(dst, src net.Conn, counter *uint64, metric prometheus.Counter) {
+	n, err := cc.copy(dst, src, counter, metric)
+	log.Printf("%v-> %v ended, %d bytes copied, err is %v", src.RemoteAddr(), dst.RemoteAddr(), n, err)
+	if err == nil && closeWrite(dst) == nil {
+		return
+	}
+	cc.endDirection(src, err)
+	cc.Conn1.Close()
+	cc.Conn2.Close()
+}
+
+// closeWrite shuts down the writing side of conn if it is supported, e.g. by TCP and TLS connections
+// and streams over multiplexed data connections
+func closeWrite(conn net.Conn) error {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		return c.CloseWrite()
+	}
+	return fmt.Errorf("%T doesn't support half-close", conn)
 }
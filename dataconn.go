@@ -0,0 +1,360 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/yamux"
+)
+
+// worker data connection preamble, sent by worker right after connecting to reflector,
+// the first byte is the connection type:
+//   - dataConnPlain: a data connection for single cross connection, followed by the one-time token of
+//     the cross connection issued in CreateWorkerCrossReq
+//   - dataConnMux: a multiplexed data connection, followed by 1 byte worker ID length, the worker ID and
+//     the worker's data token issued in Signon;
+//     each stream over it starts with a stream header, see streamCross and streamAbort
+//   - dataConnPool: an idle pre-dialed data connection, followed by 1 byte worker ID length, the worker ID,
+//     the worker's data token and 4 bytes big endian pool connection ID
+const (
+	dataConnPlain byte = 1
+	dataConnMux   byte = 2
+	dataConnPool  byte = 3
+)
+
+// stream header over multiplexed data connection, the first byte is the stream type:
+//   - streamCross: a stream of cross connection opened by worker, followed by the one-time token of
+//     the cross connection
+//   - streamAbort: opened by either side to abort a stream it has closed, followed by 4 bytes big endian
+//     ID of the aborted stream
+const (
+	streamCross byte = 1
+	streamAbort byte = 2
+)
+
+var streamPayloadLen = map[byte]int{
+	streamCross: tokenLen,
+	streamAbort: 4,
+}
+
+const (
+	preambleTimeout = 10 * time.Second
+	tokenLen        = 16
+)
+
+type preamble struct {
+	Type       byte
+	WorkerID   string
+	Token      []byte //cross connection token for plain, worker data token for mux and pool
+	PoolConnID uint32
+}
+
+// newToken returns a random token
+func newToken() []byte {
+	buf := make([]byte, tokenLen)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("failed to generate token, %v", err))
+	}
+	return buf
+}
+
+func writePlainPreamble(conn net.Conn, token []byte) error {
+	if len(token) != tokenLen {
+		return fmt.Errorf("invalid token length %d", len(token))
+	}
+	_, err := conn.Write(append([]byte{dataConnPlain}, token...))
+	return err
+}
+
+func writeMuxPreamble(conn net.Conn, workerID string, token []byte) error {
+	buf, err := workerPreamble(dataConnMux, workerID, token)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(buf)
+	return err
+}
+
+func writePoolPreamble(conn net.Conn, workerID string, token []byte, id uint32) error {
+	buf, err := workerPreamble(dataConnPool, workerID, token)
+	if err != nil {
+		return err
+	}
+	idbuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(idbuf, id)
+	_, err = conn.Write(append(buf, idbuf...))
+	return err
+}
+
+func workerPreamble(conntype byte, workerID string, token []byte) ([]byte, error) {
+	if len(workerID) > 255 {
+		return nil, fmt.Errorf("worker ID %v is too long", workerID)
+	}
+	if len(token) != tokenLen {
+		return nil, fmt.Errorf("invalid token length %d", len(token))
+	}
+	buf := append([]byte{conntype, byte(len(workerID))}, workerID...)
+	return append(buf, token...), nil
+}
+
+// readPreamble reads the data connection preamble from conn
+func readPreamble(conn net.Conn) (*preamble, error) {
+	conn.SetReadDeadline(time.Now().Add(preambleTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+		return nil, fmt.Errorf("failed to read preamble, %w", err)
+	}
+	p := &preamble{Type: buf[0], Token: make([]byte, tokenLen)}
+	switch p.Type {
+	case dataConnPlain:
+	case dataConnMux, dataConnPool:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return nil, fmt.Errorf("failed to read worker ID length, %w", err)
+		}
+		id := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, id); err != nil {
+			return nil, fmt.Errorf("failed to read worker ID, %w", err)
+		}
+		p.WorkerID = string(id)
+	default:
+		return nil, fmt.Errorf("unknown data connection type %d", p.Type)
+	}
+	if _, err := io.ReadFull(conn, p.Token); err != nil {
+		return nil, fmt.Errorf("failed to read token, %w", err)
+	}
+	if p.Type == dataConnPool {
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, fmt.Errorf("failed to read pool connection ID, %w", err)
+		}
+		p.PoolConnID = binary.BigEndian.Uint32(buf)
+	}
+	return p, nil
+}
+
+func writeStreamHeader(stream net.Conn, streamType byte, payload []byte) error {
+	if l, ok := streamPayloadLen[streamType]; !ok || len(payload) != l {
+		return fmt.Errorf("invalid stream header type %d with payload length %d", streamType, len(payload))
+	}
+	_, err := stream.Write(append([]byte{streamType}, payload...))
+	return err
+}
+
+func readStreamHeader(stream net.Conn) (byte, []byte, error) {
+	stream.SetReadDeadline(time.Now().Add(preambleTimeout))
+	defer stream.SetReadDeadline(time.Time{})
+	t := make([]byte, 1)
+	if _, err := io.ReadFull(stream, t); err != nil {
+		return 0, nil, fmt.Errorf("failed to read stream header, %w", err)
+	}
+	l, ok := streamPayloadLen[t[0]]
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid stream type %d", t[0])
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		return 0, nil, fmt.Errorf("failed to read stream header, %w", err)
+	}
+	return t[0], buf, nil
+}
+
+// newMuxConfig returns yamux config of multiplexed data connections; a locally closed stream is not reset
+// after a timeout, since a stream half-closed by CloseWrite stays readable until the peer closes it too,
+// and a stream closed by Close is aborted on the peer
+func newMuxConfig() *yamux.Config {
+	cfg := yamux.DefaultConfig()
+	cfg.StreamCloseTimeout = 0
+	return cfg
+}
+
+// muxSession is a multiplexed data connection, it keeps streams opened or accepted over it by stream ID so that
+// they could be aborted by the peer
+type muxSession struct {
+	*yamux.Session
+	streams    map[uint32]*muxStream
+	streamLock *sync.Mutex
+}
+
+func newMuxSession(sess *yamux.Session) *muxSession {
+	return &muxSession{
+		Session:    sess,
+		streams:    make(map[uint32]*muxStream),
+		streamLock: new(sync.Mutex),
+	}
+}
+
+func (ms *muxSession) track(stream *yamux.Stream) *muxStream {
+	s := &muxStream{Stream: stream, sess: ms, lock: new(sync.Mutex)}
+	ms.streamLock.Lock()
+	ms.streams[stream.StreamID()] = s
+	ms.streamLock.Unlock()
+	return s
+}
+
+func (ms *muxSession) forget(s *muxStream) {
+	ms.streamLock.Lock()
+	if ms.streams[s.StreamID()] == s {
+		delete(ms.streams, s.StreamID())
+	}
+	ms.streamLock.Unlock()
+}
+
+// openCrossStream opens a stream for the cross connection with one-time token
+func (ms *muxSession) openCrossStream(token []byte) (*muxStream, error) {
+	stream, err := ms.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	s := ms.track(stream)
+	if err = writeStreamHeader(s, streamCross, token); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// serve accepts streams opened by the peer until the session ends: an abort stream aborts the stream in it,
+// a cross connection stream is passed to onCross with its one-time token, it is refused if onCross is nil
+func (ms *muxSession) serve(onCross func(token []byte, stream *muxStream)) {
+	for {
+		stream, err := ms.AcceptStream()
+		if err != nil {
+			ms.Close()
+			return
+		}
+		go func() {
+			s := ms.track(stream)
+			streamType, payload, err := readStreamHeader(s)
+			switch {
+			case err != nil:
+			case streamType == streamAbort:
+				ms.forget(s)
+				stream.Close()
+				ms.abort(binary.BigEndian.Uint32(payload))
+				return
+			case onCross == nil:
+				err = fmt.Errorf("unexpected cross connection stream")
+			default:
+				onCross(payload, s)
+				return
+			}
+			log.Printf("failed to accept stream over %v, %v", ms.RemoteAddr(), err)
+			pairingFailures.Inc()
+			s.Close()
+		}()
+	}
+}
+
+// abort aborts the stream id at the request of the peer
+func (ms *muxSession) abort(id uint32) {
+	ms.streamLock.Lock()
+	s, ok := ms.streams[id]
+	delete(ms.streams, id)
+	ms.streamLock.Unlock()
+	if ok {
+		s.abort()
+	}
+}
+
+// sendAbort asks the peer to abort stream id, the peer closes the cross connection of the stream
+func (ms *muxSession) sendAbort(id uint32) {
+	stream, err := ms.OpenStream()
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, id)
+	writeStreamHeader(stream, streamAbort, payload)
+}
+
+// muxStream is a stream over multiplexed data connection supporting half-close: CloseWrite sends FIN while
+// the stream stays readable; Close aborts the stream on the peer unless the stream has ended in both directions,
+// so that the peer closes its cross connection as if a TCP data connection was reset
+type muxStream struct {
+	*yamux.Stream
+	sess    *muxSession
+	lock    *sync.Mutex //protects the fields below
+	wclosed bool        //CloseWrite was called
+	rclosed bool        //EOF was read
+	closed  bool        //Close was called
+	aborted bool        //aborted by the peer
+	onAbort func()
+}
+
+func (s *muxStream) Read(b []byte) (int, error) {
+	s.lock.Lock()
+	closed := s.closed
+	s.lock.Unlock()
+	if closed {
+		return 0, net.ErrClosed
+	}
+	n, err := s.Stream.Read(b)
+	if err != nil {
+		s.lock.Lock()
+		if s.closed {
+			err = net.ErrClosed
+		} else if err == io.EOF {
+			s.rclosed = true
+		}
+		s.lock.Unlock()
+	}
+	return n, err
+}
+
+func (s *muxStream) CloseWrite() error {
+	s.lock.Lock()
+	s.wclosed = true
+	s.lock.Unlock()
+	return s.Stream.Close()
+}
+
+func (s *muxStream) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	abort := !s.aborted && !(s.wclosed && s.rclosed)
+	s.lock.Unlock()
+	s.sess.forget(s)
+	if abort {
+		go s.sess.sendAbort(s.StreamID())
+	}
+	err := s.Stream.Close()
+	//wake up a blocked Read, it returns net.ErrClosed
+	s.Stream.SetReadDeadline(time.Now())
+	return err
+}
+
+// setAbortHandler sets f to be called when the stream is aborted by the peer, f is called right away if
+// the stream is already aborted
+func (s *muxStream) setAbortHandler(f func()) {
+	s.lock.Lock()
+	s.onAbort = f
+	aborted := s.aborted
+	s.lock.Unlock()
+	if aborted {
+		f()
+	}
+}
+
+func (s *muxStream) abort() {
+	s.lock.Lock()
+	s.aborted = true
+	f := s.onAbort
+	s.lock.Unlock()
+	log.Printf("stream %d over %v aborted by peer", s.StreamID(), s.sess.RemoteAddr())
+	if f != nil {
+		f()
+	} else {
+		s.Close()
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/yamux"
+)
+
+const muxTestTimeout = 2 * time.Second
+
+// muxPair returns the two ends of a cross connection stream opened by worker over a multiplexed data connection
+func muxPair(t *testing.T) (worker, refl *muxStream) {
+	c1, c2 := net.Pipe()
+	cs, err := yamux.Client(c1, newMuxConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := yamux.Server(c2, newMuxConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsess, rsess := newMuxSession(cs), newMuxSession(ss)
+	t.Cleanup(func() {
+		wsess.Close()
+		rsess.Close()
+	})
+	accepted := make(chan *muxStream, 1)
+	go wsess.serve(nil)
+	go rsess.serve(func(token []byte, stream *muxStream) {
+		accepted <- stream
+	})
+	worker, err = wsess.openCrossStream(newToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case refl = <-accepted:
+	case <-time.After(muxTestTimeout):
+		t.Fatal("stream not accepted")
+	}
+	return worker, refl
+}
+
+func TestMuxStreamHalfClose(t *testing.T) {
+	worker, refl := muxPair(t)
+	if _, err := refl.Write([]byte("request")); err != nil {
+		t.Fatal(err)
+	}
+	if err := refl.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	worker.SetReadDeadline(time.Now().Add(muxTestTimeout))
+	b, err := io.ReadAll(worker)
+	if err != nil || string(b) != "request" {
+		t.Fatalf("got %q and error %v, expect request followed by EOF", b, err)
+	}
+	if _, err = worker.Write([]byte("response")); err != nil {
+		t.Fatalf("write after peer half-closed failed, %v", err)
+	}
+	worker.CloseWrite()
+	refl.SetReadDeadline(time.Now().Add(muxTestTimeout))
+	if b, err = io.ReadAll(refl); err != nil || string(b) != "response" {
+		t.Fatalf("got %q and error %v after half-close, expect response", b, err)
+	}
+	aborted := make(chan struct{})
+	worker.setAbortHandler(func() { close(aborted) })
+	refl.Close()
+	worker.Close()
+	select {
+	case <-aborted:
+		t.Error("stream ended in both directions is aborted on close")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMuxStreamCloseAborts(t *testing.T) {
+	for _, halfClosed := range []bool{false, true} {
+		worker, refl := muxPair(t)
+		aborted := make(chan struct{})
+		worker.setAbortHandler(func() { close(aborted) })
+		if halfClosed {
+			refl.CloseWrite()
+		}
+		refl.Close()
+		select {
+		case <-aborted:
+		case <-time.After(muxTestTimeout):
+			t.Fatalf("peer is not aborted on close, half-closed before is %v", halfClosed)
+		}
+		//the aborted side is not aborted back when it closes
+		worker.Close()
+		if _, err := refl.Read(make([]byte, 1)); err == nil {
+			t.Errorf("read after close succeeded")
+		}
+	}
+}
+
+func TestMuxStreamAbortedBeforeHandler(t *testing.T) {
+	worker, refl := muxPair(t)
+	worker.Close()
+	time.Sleep(100 * time.Millisecond)
+	aborted := make(chan struct{})
+	refl.setAbortHandler(func() { close(aborted) })
+	select {
+	case <-aborted:
+	case <-time.After(muxTestTimeout):
+		t.Fatal("abort handler set after abort is not called")
+	}
+}
+
+func TestStreamHeader(t *testing.T) {
+	token := newToken()
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	go func() {
+		writeStreamHeader(c1, streamCross, token)
+		c1.Write([]byte{9, 0, 0})
+	}()
+	streamType, payload, err := readStreamHeader(c2)
+	if err != nil || streamType != streamCross || !bytes.Equal(payload, token) {
+		t.Fatalf("got type %d, payload %x and error %v", streamType, payload, err)
+	}
+	if _, _, err = readStreamHeader(c2); err == nil {
+		t.Errorf("invalid stream type is accepted")
+	}
+	if err = writeStreamHeader(c1, streamAbort, token); err == nil {
+		t.Errorf("abort header with token payload is accepted")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"rproxy/api"
+	"time"
+)
+
+const (
+	failureReplyTimeout = 3 * time.Second
+	frontendTimeout     = 10 * time.Second
+)
+
+// clientHandshake negotiates with client of conn accepted on dynamic tunnel t in the protocol of its mode,
+// returns the destination requested by client
+func clientHandshake(conn net.Conn, t *Tunnel) (string, error) {
+	switch t.Mode {
+	case tunnelModeSOCKS5:
+		return socks5Handshake(conn, t.Username, t.Password)
+	case tunnelModeConnect:
+		return connectHandshake(conn, t.Username, t.Password)
+	}
+	return "", fmt.Errorf("%v tunnel %v doesn't take destination from client", t.Mode, t.Name)
+}
+
+// replySuccess tells client of conn that its server is connected in the way of tunnel mode,
+// before any data is relayed
+func replySuccess(conn net.Conn, mode string) error {
+	switch mode {
+	case tunnelModeSOCKS5:
+		conn.SetWriteDeadline(time.Now().Add(failureReplyTimeout))
+		defer conn.SetWriteDeadline(time.Time{})
+		return socks5Reply(conn, socks5RepSucceeded)
+	case tunnelModeConnect:
+		conn.SetWriteDeadline(time.Now().Add(failureReplyTimeout))
+		defer conn.SetWriteDeadline(time.Time{})
+		_, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return err
+	}
+	return nil
+}
+
+// replyFailure tells client of conn that its server can't be reached because of st in the way of tunnel mode,
+// then drains what client has sent for a while so that the reply is not discarded by a reset; caller closes conn
+func replyFailure(conn net.Conn, mode string, st api.CrossStatus, reason string) {
+	conn.SetDeadline(time.Now().Add(failureReplyTimeout))
+	switch mode {
+	case tunnelModeHTTP, tunnelModeConnect:
+		if err := writeHTTPResponse(conn, httpFailureCode(st), "", "rproxy: "+reason+"\n"); err != nil {
+			return
+		}
+	case tunnelModeSOCKS5:
+		if err := socks5Reply(conn, socks5ReplyCode(st)); err != nil {
+			return
+		}
+	default:
+		return
+	}
+	if tcpconn, ok := conn.(*net.TCPConn); ok {
+		tcpconn.CloseWrite()
+	}
+	io.Copy(io.Discard, conn)
+}
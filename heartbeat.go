@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"rproxy/api"
+	"time"
+)
+
+const (
+	defaultHeartbeatInterval = 10 * time.Second
+	defaultHeartbeatMiss     = 3
+)
+
+func (refl *Reflector) Heartbeat(stream api.RProxyAPI_HeartbeatServer) error {
+	w, err := refl.authWorkerStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer log.Printf("heartbeat routine for worker %v ended", w.ID)
+	for {
+		hb, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		refl.workerLock.Lock()
+		w.lastSeen = time.Now()
+		refl.workerLock.Unlock()
+		if err = stream.Send(hb); err != nil {
+			return err
+		}
+	}
+}
+
+// monitorWorkers marks workers without heartbeat for hbMiss intervals offline
+func (refl *Reflector) monitorWorkers() {
+	ticker := time.NewTicker(refl.hbInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		//lastSeen is updated under workerLock, so it is copied while holding the lock
+		type deadWorker struct {
+			w        *RemoteWorker
+			lastSeen time.Time
+		}
+		var dead []deadWorker
+		refl.workerLock.RLock()
+		for _, w := range refl.Workers {
+			if time.Since(w.lastSeen) > refl.hbInterval*time.Duration(refl.hbMiss) {
+				dead = append(dead, deadWorker{w, w.lastSeen})
+			}
+		}
+		refl.workerLock.RUnlock()
+		for _, d := range dead {
+			log.Printf("no heartbeat from worker %v since %v", d.w.ID, d.lastSeen)
+			refl.workerOffline(d.w)
+		}
+	}
+}
+
+// heartbeat sends heartbeat to reflector every interval, returns error if there is no reply for miss intervals
+// or the heartbeat stream fails; it blocks until ctx is done if interval is 0
+func (w *Worker) heartbeat(ctx context.Context, interval time.Duration, miss int) error {
+	if interval == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	stream, err := w.clnt.Heartbeat(w.workerStreamContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to create heartbeat stream, %w", err)
+	}
+	replies := make(chan struct{}, 1)
+	errch := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				errch <- err
+				return
+			}
+			select {
+			case replies <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastReply := time.Now()
+	var seq uint64
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(lastReply) > interval*time.Duration(miss) {
+				return fmt.Errorf("no heartbeat reply from reflector since %v", lastReply)
+			}
+			seq++
+			if err := stream.Send(&api.HeartbeatMsg{Seq: seq}); err != nil {
+				return fmt.Errorf("failed to send heartbeat, %w", err)
+			}
+		case <-replies:
+			lastReply = time.Now()
+		case err := <-errch:
+			return fmt.Errorf("heartbeat stream failed, %w", err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
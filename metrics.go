@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace = "rproxy"
+	metricsPath      = "/metrics"
+)
+
+var (
+	activeCrossConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "active_crossconnections",
+		Help:      "Number of running cross connections.",
+	})
+	acceptedClients = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "accepted_clients_total",
+		Help:      "Total number of client connections accepted by reflector.",
+	}, []string{"tunnel"})
+	rejectedClients = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rejected_clients_total",
+		Help:      "Total number of client connections rejected by reflector ACL.",
+	}, []string{"tunnel"})
+	pairingFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pairing_failures_total",
+		Help:      "Total number of worker data connections or reports not matching any cross connection.",
+	})
+	workerDialFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "worker_dial_failures_total",
+		Help:      "Total number of failures of worker connecting to server or reflector for a cross connection.",
+	}, []string{"peer"})
+	transferredBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transferred_bytes_total",
+		Help:      "Total bytes transferred by cross connections, in is from client to server, out is from server to client.",
+	}, []string{"direction"})
+	pairingLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "pairing_latency_seconds",
+		Help:      "Time from accepting a client connection to pairing it with a worker data connection.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	})
+	signedOnWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "signed_on_workers",
+		Help:      "Number of workers signed on to reflector.",
+	})
+
+	bytesIn  = transferredBytes.WithLabelValues("in")
+	bytesOut = transferredBytes.WithLabelValues("out")
+)
+
+func init() {
+	prometheus.MustRegister(activeCrossConnections, acceptedClients, rejectedClients, pairingFailures, workerDialFailures,
+		transferredBytes, pairingLatency, signedOnWorkers)
+}
+
+// serveMetrics serves prometheus metrics on addr at metricsPath
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.Handler())
+	log.Printf("serving metrics on %v%v", addr, metricsPath)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
@@ -2,165 +2,754 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
 	"rproxy/api"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/yamux"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// RemoteWorker is a worker signed on to the reflector
+type RemoteWorker struct {
+	ID                       string
+	Addr                     net.IP
+	createWorkerCrossReqChan chan *api.CreateWorkerCrossReq
+	done                     chan struct{}
+	idleConns                []*poolConn //pre-dialed idle data connections from the worker, oldest first
+	DataToken                []byte      //presented by the worker on multiplexed and pool data connections
+	SessionID                string
+	lastSeen                 time.Time //time of sign on or last heartbeat
+}
+
+// poolConn is an idle pre-dialed data connection from worker
+type poolConn struct {
+	ID   uint32
+	Conn net.Conn
+}
+
 type Reflector struct {
 	api.UnimplementedRProxyAPIServer
-	CrossConnections         map[int]*CrossConnection //key is CC ID
-	toClient, toWorker       *net.TCPListener
-	createWorkerCrossReqChan chan *api.CreateWorkerCrossReq
-	WorkerAddr               net.IP
-	workerLock               *sync.RWMutex
-	currentCCID              int
-	fromWorkerConnQ          map[int]*net.TCPConn //key is the remote tcp port
-	fromWorkerConnQLock      *sync.RWMutex
+	CrossConnections map[int]*CrossConnection //key is CC ID
+	Tunnels          map[string]*Tunnel       //key is tunnel name
+	toWorker         *net.TCPListener
+	tlsConf          *tls.Config              //TLS config for API and worker data connections, nil means plaintext
+	Workers          map[string]*RemoteWorker //key is worker ID
+	workerLock       *sync.RWMutex
+	currentCCID      int
+	nextWorker       int
+	ccTokens         map[string]int //one-time tokens of cross connections waiting for worker data connection, value is CC ID
+	secret           string         //pre-shared secret workers must present in Signon, empty means no authentication
+	adminSecret      string         //secret admin RPC must present, empty means admin API is disabled
+	hbInterval       time.Duration  //heartbeat interval of workers, 0 means heartbeat is disabled
+	hbMiss           int            //number of missed heartbeats before a worker is considered offline
+	pairTimeout      time.Duration  //client connection not paired with worker data connection within it is closed, 0 means no timeout
+	idleTimeout      time.Duration  //idle timeout of cross connections, 0 means no timeout
+	maxLifetime      time.Duration  //max lifetime of cross connections, 0 means no limit
+	acl              ACL            //source address ACL of client connections on all tunnels
+	grpcServer       *grpc.Server
+	closing          int32 //set to 1 when shutting down, accessed atomically
 }
 
 const (
 	createWorkerCrossReqChanDepth = 128
+	defaultPairTimeout            = 30 * time.Second
+	janitorInterval               = time.Second
 )
 
-func (refl *Reflector) Signon(ctx context.Context, req *api.Empty) (*api.Empty, error) {
+func (refl *Reflector) Signon(ctx context.Context, req *api.SignonReq) (*api.SignonResp, error) {
+	if req.WorkerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "worker ID is empty")
+	}
 	p, _ := peer.FromContext(ctx)
-	refl.ResetWorker(p.Addr.(*net.TCPAddr).IP)
-	return &api.Empty{}, nil
+	if !checkSecret(refl.secret, req.Secret) {
+		log.Printf("rejected signon of worker %v from %v, invalid secret", req.WorkerID, p.Addr)
+		return nil, status.Error(codes.Unauthenticated, "invalid secret")
+	}
+	w := refl.RegisterWorker(req.WorkerID, p.Addr.(*net.TCPAddr).IP)
+	return &api.SignonResp{
+		DataToken:         w.DataToken,
+		SessionID:         w.SessionID,
+		HeartbeatInterval: uint32(refl.hbInterval / time.Millisecond),
+		HeartbeatMiss:     uint32(refl.hbMiss),
+	}, nil
 }
-func (refl *Reflector) Signoff(ctx context.Context, req *api.Empty) (*api.Empty, error) {
-	refl.ResetWorker(nil)
+func (refl *Reflector) Signoff(ctx context.Context, req *api.WorkerReq) (*api.Empty, error) {
+	if _, err := refl.authWorker(req.WorkerID, req.SessionID); err != nil {
+		return nil, err
+	}
+	refl.RemoveWorker(req.WorkerID)
 	return &api.Empty{}, nil
 }
-func (refl *Reflector) CreateWorkerCross(_ *api.Empty, stream api.RProxyAPI_CreateWorkerCrossServer) error {
-	defer log.Print("createworker routine ended")
-	for req := range refl.createWorkerCrossReqChan {
-		if err := stream.Send(req); err != nil {
-			log.Fatalf("failed to send create worker channel, %v", err)
-			return err
+func (refl *Reflector) CreateWorkerCross(req *api.WorkerReq, stream api.RProxyAPI_CreateWorkerCrossServer) error {
+	w, err := refl.authWorker(req.WorkerID, req.SessionID)
+	if err != nil {
+		return err
+	}
+	defer log.Printf("createworker routine for worker %v ended", req.WorkerID)
+	for {
+		select {
+		case req := <-w.createWorkerCrossReqChan:
+			if err := stream.Send(req); err != nil {
+				log.Printf("failed to send create worker channel of worker %v, %v", w.ID, err)
+				refl.workerOffline(w, req)
+				return err
+			}
+		case <-stream.Context().Done():
+			log.Printf("create worker channel of worker %v closed, %v", w.ID, stream.Context().Err())
+			refl.workerOffline(w)
+			return stream.Context().Err()
+		case <-w.done:
+			return nil
 		}
 	}
-	return nil
 }
 
 func (refl *Reflector) ReportWorkerCross(stream api.RProxyAPI_ReportWorkerCrossServer) error {
-	defer log.Print("report worker routine ended")
+	w, err := refl.authWorkerStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer log.Printf("report worker routine for worker %v ended", w.ID)
 	for {
-		worker, err := stream.Recv()
+		report, err := stream.Recv()
 		if err != nil {
-			log.Fatalf("failed to recv from report worker channel, %v", err)
+			log.Printf("failed to recv from report worker channel of worker %v, %v", w.ID, err)
+			refl.workerOffline(w)
 			return err
 		}
-
-		refl.fromWorkerConnQLock.Lock()
-		if conn, ok := refl.fromWorkerConnQ[int(worker.Port)]; ok {
-			refl.workerLock.RLock()
-			if cc, ok := refl.CrossConnections[int(worker.ID)]; ok {
-				err = cc.Complete(conn)
-				if err != nil {
-					log.Printf("%v is already completed, can't add conn %v",
-						cc.String(), conn.RemoteAddr())
-				} else {
-					//start CC
-					go cc.Run()
-					//remove from waiting Q
-					delete(refl.fromWorkerConnQ, int(worker.Port))
+		refl.workerLock.Lock()
+		cc, ok := refl.CrossConnections[int(report.ID)]
+		if !ok || cc.WorkerID != w.ID {
+			refl.workerLock.Unlock()
+			log.Printf("worker %v reported unknown crossconnection %d", w.ID, report.ID)
+			pairingFailures.Inc()
+			continue
+		}
+		if report.Status == api.CrossStatus_CROSS_OK {
+			if cc.waitReport {
+				cc.waitReport = false
+				//otherwise cc is started once the worker data connection arrives
+				if cc.Conn2 != nil {
+					go refl.startCC(cc)
 				}
 			}
-			refl.workerLock.RUnlock()
+			refl.workerLock.Unlock()
+			continue
 		}
-		refl.fromWorkerConnQLock.Unlock()
+		refl.forgetCC(cc)
+		refl.workerLock.Unlock()
+		log.Printf("worker %v failed to create %v, %v: %v", w.ID, cc, report.Status, report.Error)
+		go refl.failCC(cc, report)
+	}
+}
+
+// failCC answers the client of cross connection cc failed on worker according to mode of its tunnel,
+// and closes cc
+func (refl *Reflector) failCC(cc *CrossConnection, report *api.ReportWorkerCrossReq) {
+	if cc.Conn2 != nil {
+		cc.Conn2.Close()
+	}
+	replyFailure(cc.Conn1, cc.Mode, report.Status, fmt.Sprintf("worker %v: %v", cc.WorkerID, report.Error))
+	cc.Conn1.Close()
+}
 
+// startCC answers the client of cross connection cc connected according to mode of its tunnel, and runs cc
+func (refl *Reflector) startCC(cc *CrossConnection) {
+	if err := replySuccess(cc.Conn1, cc.Mode); err != nil {
+		log.Printf("failed to answer client of %v, %v", cc, err)
+		refl.workerLock.Lock()
+		refl.closeCC(cc)
+		refl.workerLock.Unlock()
+		return
 	}
+	refl.runCC(cc)
 }
-func (refl *Reflector) ResetWorker(w net.IP) {
+
+// completeCC completes the cross connection with one-time token with worker data connection conn and starts it,
+// if workerID is not empty, the cross connection must belong to it
+func (refl *Reflector) completeCC(token []byte, workerID string, conn net.Conn) error {
 	refl.workerLock.Lock()
 	defer refl.workerLock.Unlock()
-	refl.CrossConnections = make(map[int]*CrossConnection)
-	refl.WorkerAddr = w
+	ccid, ok := refl.ccTokens[string(token)]
+	if !ok {
+		return fmt.Errorf("unknown crossconnection token")
+	}
+	cc, ok := refl.CrossConnections[ccid]
+	if !ok {
+		delete(refl.ccTokens, string(token))
+		return fmt.Errorf("crossconnection %d not found", ccid)
+	}
+	if workerID != "" && cc.WorkerID != workerID {
+		return fmt.Errorf("%v doesn't belong to worker %v", cc, workerID)
+	}
+	if err := cc.Complete(conn); err != nil {
+		return err
+	}
+	delete(refl.ccTokens, string(token))
+	pairingLatency.Observe(time.Since(cc.StartTime).Seconds())
+	//start CC, unless waiting for worker's report
+	if !cc.waitReport {
+		go refl.startCC(cc)
+	}
+	return nil
 }
+
+// checkDataToken returns error if worker workerID is not signed on or token is not its data token
+func (refl *Reflector) checkDataToken(workerID string, token []byte) error {
+	refl.workerLock.RLock()
+	defer refl.workerLock.RUnlock()
+	w, ok := refl.Workers[workerID]
+	if !ok {
+		return fmt.Errorf("worker %v is not signed on", workerID)
+	}
+	if subtle.ConstantTimeCompare(w.DataToken, token) != 1 {
+		return fmt.Errorf("invalid data token of worker %v", workerID)
+	}
+	return nil
+}
+
+// RegisterWorker adds worker id to the registry, replacing any existing worker with same id
+func (refl *Reflector) RegisterWorker(id string, addr net.IP) *RemoteWorker {
+	refl.workerLock.Lock()
+	defer refl.workerLock.Unlock()
+	refl.removeWorker(id)
+	w := &RemoteWorker{
+		ID:                       id,
+		Addr:                     addr,
+		createWorkerCrossReqChan: make(chan *api.CreateWorkerCrossReq, createWorkerCrossReqChanDepth),
+		done:                     make(chan struct{}),
+		DataToken:                newToken(),
+		SessionID:                newSessionID(),
+		lastSeen:                 time.Now(),
+	}
+	refl.Workers[id] = w
+	signedOnWorkers.Set(float64(len(refl.Workers)))
+	log.Printf("worker %v signed on from %v", id, addr)
+	return w
+}
+
+// RemoveWorker removes worker id from the registry
+func (refl *Reflector) RemoveWorker(id string) {
+	refl.workerLock.Lock()
+	defer refl.workerLock.Unlock()
+	refl.removeWorker(id)
+}
+
+// workerOffline removes worker w from the registry if it is still the registered one with its ID,
+// pending are create requests taken from w but not delivered
+func (refl *Reflector) workerOffline(w *RemoteWorker, pending ...*api.CreateWorkerCrossReq) {
+	refl.workerLock.Lock()
+	defer refl.workerLock.Unlock()
+	for _, req := range pending {
+		refl.redispatch(req)
+	}
+	if cur, ok := refl.Workers[w.ID]; ok && cur == w {
+		log.Printf("worker %v is offline", w.ID)
+		refl.removeWorker(w.ID)
+	}
+}
+
+// removeWorker removes worker id from the registry, its pending create requests are dispatched to other workers,
+// client connections of its cross connections waiting for data connection are closed;
+// requires caller to hold workerLock
+func (refl *Reflector) removeWorker(id string) {
+	w, ok := refl.Workers[id]
+	if !ok {
+		return
+	}
+	close(w.done)
+	for _, pc := range w.idleConns {
+		pc.Conn.Close()
+	}
+	delete(refl.Workers, id)
+	signedOnWorkers.Set(float64(len(refl.Workers)))
+	for {
+		select {
+		case req := <-w.createWorkerCrossReqChan:
+			refl.redispatch(req)
+			continue
+		default:
+		}
+		break
+	}
+	for token, ccid := range refl.ccTokens {
+		if cc, ok := refl.CrossConnections[ccid]; !ok || cc.WorkerID == id {
+			delete(refl.ccTokens, token)
+		}
+	}
+	for ccid, cc := range refl.CrossConnections {
+		if cc.WorkerID == id && cc.pending() {
+			log.Printf("worker %v removed, closing client connection of %v", id, cc)
+			cc.Conn1.Close()
+			delete(refl.CrossConnections, ccid)
+		}
+	}
+	log.Printf("worker %v removed", id)
+}
+
+// redispatch dispatches create request req not delivered to its worker to another worker,
+// the client connection is closed if it is not possible; caller must hold workerLock
+func (refl *Reflector) redispatch(req *api.CreateWorkerCrossReq) {
+	cc, ok := refl.CrossConnections[int(req.ID)]
+	if !ok {
+		return
+	}
+	//cross connection bound to a pool data connection can't be moved to another worker
+	if req.PoolConnID == 0 {
+		if t, ok := refl.Tunnels[cc.Tunnel]; !ok {
+			log.Printf("tunnel of %v is removed", cc)
+		} else if w := refl.pickWorker(t); w != nil {
+			select {
+			case w.createWorkerCrossReqChan <- req:
+				log.Printf("%v is redispatched from worker %v to %v", cc, cc.WorkerID, w.ID)
+				cc.WorkerID = w.ID
+				return
+			default:
+			}
+		}
+	}
+	log.Printf("can't redispatch %v of worker %v, closing client connection", cc, cc.WorkerID)
+	refl.closeCC(cc)
+}
+
+// closeCC closes cross connection cc and removes it; caller must hold workerLock
+func (refl *Reflector) closeCC(cc *CrossConnection) {
+	cc.Conn1.Close()
+	if cc.Conn2 != nil {
+		cc.Conn2.Close()
+	}
+	refl.forgetCC(cc)
+}
+
+// forgetCC removes cross connection cc and its one-time token without closing it; caller must hold workerLock
+func (refl *Reflector) forgetCC(cc *CrossConnection) {
+	delete(refl.CrossConnections, cc.ID)
+	for token, ccid := range refl.ccTokens {
+		if ccid == cc.ID {
+			delete(refl.ccTokens, token)
+		}
+	}
+}
+
+// runCC runs cross connection cc, and removes it from the registry when it ends
+func (refl *Reflector) runCC(cc *CrossConnection) {
+	cc.Run()
+	refl.workerLock.Lock()
+	if refl.CrossConnections[cc.ID] == cc {
+		delete(refl.CrossConnections, cc.ID)
+	}
+	refl.workerLock.Unlock()
+}
+
+// reapUnpaired closes cross connections not paired with a worker data connection within pairTimeout
+func (refl *Reflector) reapUnpaired() {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		refl.workerLock.Lock()
+		for _, cc := range refl.CrossConnections {
+			if cc.pending() && time.Since(cc.StartTime) > refl.pairTimeout {
+				log.Printf("%v is not paired by worker %v within %v, closing client connection", cc, cc.WorkerID, refl.pairTimeout)
+				pairingFailures.Inc()
+				refl.closeCC(cc)
+			}
+		}
+		//tokens of cross connections already gone
+		for token, ccid := range refl.ccTokens {
+			if _, ok := refl.CrossConnections[ccid]; !ok {
+				delete(refl.ccTokens, token)
+			}
+		}
+		refl.workerLock.Unlock()
+	}
+}
+
+// pickWorker returns the next signed on worker eligible for tunnel t in round-robin order, nil if there is none;
+// caller must hold workerLock
+func (refl *Reflector) pickWorker(t *Tunnel) *RemoteWorker {
+	ids := make([]string, 0, len(refl.Workers))
+	for id := range refl.Workers {
+		if t.servedBy(id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+	refl.nextWorker++
+	return refl.Workers[ids[refl.nextWorker%len(ids)]]
+}
+
 func (refl *Reflector) ListenForWorker() {
 	for {
 		newworkerc, err := refl.toWorker.AcceptTCP()
 		if err != nil {
+			if refl.isClosing() {
+				return
+			}
 			log.Fatalf("failed to accept client conn, %v", err)
 		}
-		refl.fromWorkerConnQLock.Lock()
-		refl.fromWorkerConnQ[newworkerc.RemoteAddr().(*net.TCPAddr).Port] = newworkerc
-		refl.fromWorkerConnQLock.Unlock()
-		log.Printf("got a new worker data connection %v", newworkerc.RemoteAddr())
+		go refl.handleWorkerConn(newworkerc)
+	}
+}
+
+func (refl *Reflector) handleWorkerConn(conn net.Conn) {
+	if refl.tlsConf != nil {
+		tlsconn := tls.Server(conn, refl.tlsConf)
+		tlsconn.SetDeadline(time.Now().Add(preambleTimeout))
+		if err := tlsconn.Handshake(); err != nil {
+			log.Printf("TLS handshake with worker data connection %v failed, %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		tlsconn.SetDeadline(time.Time{})
+		conn = tlsconn
+	}
+	p, err := readPreamble(conn)
+	if err != nil {
+		log.Printf("invalid worker data connection %v, %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	switch p.Type {
+	case dataConnPlain:
+		log.Printf("got a new worker data connection %v", conn.RemoteAddr())
+		if err = refl.completeCC(p.Token, "", conn); err != nil {
+			log.Printf("failed to pair worker data connection %v, %v", conn.RemoteAddr(), err)
+			pairingFailures.Inc()
+			conn.Close()
+		}
+		return
+	}
+	if err = refl.checkDataToken(p.WorkerID, p.Token); err != nil {
+		log.Printf("rejected worker data connection %v, %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	switch p.Type {
+	case dataConnMux:
+		log.Printf("got a new multiplexed data connection %v from worker %v", conn.RemoteAddr(), p.WorkerID)
+		refl.serveMuxSession(conn, p.WorkerID)
+	case dataConnPool:
+		refl.workerLock.Lock()
+		defer refl.workerLock.Unlock()
+		w, ok := refl.Workers[p.WorkerID]
+		if !ok {
+			log.Printf("worker %v of pool data connection %v is not signed on", p.WorkerID, conn.RemoteAddr())
+			conn.Close()
+			return
+		}
+		w.idleConns = append(w.idleConns, &poolConn{ID: p.PoolConnID, Conn: conn})
+		log.Printf("got a new idle pool data connection %v from worker %v", conn.RemoteAddr(), p.WorkerID)
+	}
+}
+
+// serveMuxSession accepts streams opened by worker workerID over multiplexed data connection conn,
+// each cross connection stream is paired with the cross connection specified in its header
+func (refl *Reflector) serveMuxSession(conn net.Conn, workerID string) {
+	sess, err := yamux.Server(conn, newMuxConfig())
+	if err != nil {
+		log.Printf("failed to create mux session over %v, %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
 	}
+	defer log.Printf("multiplexed data connection %v from worker %v ended", conn.RemoteAddr(), workerID)
+	newMuxSession(sess).serve(func(token []byte, stream *muxStream) {
+		if err := refl.completeCC(token, workerID, stream); err != nil {
+			log.Printf("failed to pair stream from worker %v, %v", workerID, err)
+			pairingFailures.Inc()
+			stream.Close()
+		}
+	})
 }
 
+// ListenForClient starts accepting client connections on all tunnels
 func (refl *Reflector) ListenForClient() {
+	refl.workerLock.RLock()
+	defer refl.workerLock.RUnlock()
+	for _, t := range refl.Tunnels {
+		refl.serveTunnel(t)
+	}
+}
+
+// serveTunnel starts the listener routine of tunnel t
+func (refl *Reflector) serveTunnel(t *Tunnel) {
+	if t.Mode == tunnelModeUDP {
+		go refl.listenForUDPClient(t)
+		return
+	}
+	go refl.listenForClient(t)
+}
+
+// listenForClient accepts client connections on listener of tunnel t, until the listener is closed by reload or
+// shutdown; the current tunnel with the same name is used for each connection since it might be updated by reload
+func (refl *Reflector) listenForClient(t *Tunnel) {
+	ln, name := t.listener, t.Name
 	for {
-		refl.workerLock.RLock()
-		if refl.WorkerAddr == nil {
-			refl.workerLock.RUnlock()
-			continue
+		newclinetc, err := ln.AcceptTCP()
+		refl.workerLock.Lock()
+		cur, ok := refl.Tunnels[name]
+		if !ok || cur.listener != ln {
+			refl.workerLock.Unlock()
+			if err == nil {
+				newclinetc.Close()
+			}
+			log.Printf("stopped accepting client conn on %v", t)
+			return
 		}
-		refl.workerLock.RUnlock()
-		newclinetc, err := refl.toClient.AcceptTCP()
 		if err != nil {
-			log.Fatalf("failed to accept client conn, %v", err)
+			refl.workerLock.Unlock()
+			if refl.isClosing() {
+				return
+			}
+			log.Fatalf("failed to accept client conn on %v, %v", cur, err)
 		}
-		log.Printf("accepted new client connection from %v", newclinetc.RemoteAddr())
-		newcc := &CrossConnection{
-			ID:    refl.currentCCID,
-			Conn1: newclinetc,
+		ip := newclinetc.RemoteAddr().(*net.TCPAddr).IP
+		if !refl.acl.Allowed(ip) || !cur.Allowed(ip) {
+			refl.workerLock.Unlock()
+			log.Printf("rejected client connection from %v on %v by ACL", newclinetc.RemoteAddr(), cur)
+			rejectedClients.WithLabelValues(name).Inc()
+			newclinetc.Close()
+			continue
 		}
-		refl.currentCCID++
-		refl.workerLock.Lock()
-		refl.CrossConnections[newcc.ID] = newcc
-		refl.workerLock.Unlock()
-		workreq := &api.CreateWorkerCrossReq{
-			ID: uint32(newcc.ID),
+		log.Printf("accepted new client connection from %v on %v", newclinetc.RemoteAddr(), cur)
+		acceptedClients.WithLabelValues(name).Inc()
+		if cur.dynamic() {
+			refl.workerLock.Unlock()
+			go refl.handshakeClient(newclinetc, cur)
+			continue
 		}
-		refl.createWorkerCrossReqChan <- workreq
+		refl.dispatch(newclinetc, cur, "")
+		refl.workerLock.Unlock()
 	}
 }
 
-func NewReflector(clientListenAddr, workerListenAddr string, apiport int) (*Reflector, error) {
-	caddr, err := net.ResolveTCPAddr("tcp", clientListenAddr)
+// handshakeClient gets the destination requested by client of conn accepted on dynamic tunnel t, and dispatches it
+func (refl *Reflector) handshakeClient(conn net.Conn, t *Tunnel) {
+	conn.SetDeadline(time.Now().Add(frontendTimeout))
+	target, err := clientHandshake(conn, t)
 	if err != nil {
-		return nil, fmt.Errorf("invalid client listen address %v, %w", clientListenAddr, err)
+		log.Printf("handshake with client %v on %v failed, %v", conn.RemoteAddr(), t, err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	log.Printf("client %v on %v requested %v", conn.RemoteAddr(), t, target)
+	refl.workerLock.Lock()
+	defer refl.workerLock.Unlock()
+	if refl.isClosing() {
+		conn.Close()
+		return
+	}
+	refl.dispatch(conn, t, target)
+}
+
+// Reload replaces tunnels of reflector with tunnels and its ACL with acl: listeners of removed tunnels are closed,
+// new listeners are created, other settings of existing tunnels are updated in place; a tunnel with changed listen
+// address gets its new listener before the old one is closed, and is kept unchanged if the new one can't be created;
+// existing cross connections are not affected
+func (refl *Reflector) Reload(tunnels []*Tunnel, acl ACL) error {
+	if len(tunnels) == 0 {
+		return fmt.Errorf("no tunnel specified")
+	}
+	newTunnels := make(map[string]*Tunnel)
+	for _, t := range tunnels {
+		if _, ok := newTunnels[t.Name]; ok {
+			return fmt.Errorf("duplicate tunnel name %v", t.Name)
+		}
+		newTunnels[t.Name] = t
+	}
+	refl.workerLock.Lock()
+	defer refl.workerLock.Unlock()
+	refl.acl = acl
+	//close removed tunnels first, so that their listen addresses could be taken by other tunnels
+	for name, old := range refl.Tunnels {
+		if _, ok := newTunnels[name]; !ok {
+			old.close()
+			delete(refl.Tunnels, name)
+			log.Printf("%v removed", old)
+		}
+	}
+	var failed []string
+	for _, t := range tunnels {
+		old, ok := refl.Tunnels[t.Name]
+		if ok && t.ListenAddr == old.ListenAddr && t.network() == old.network() {
+			t.listener, t.udpConn = old.listener, old.udpConn
+			refl.Tunnels[t.Name] = t
+			if t.String() != old.String() {
+				log.Printf("%v updated to %v", old, t)
+			}
+			continue
+		}
+		if err := t.listen(); err != nil {
+			if ok {
+				log.Printf("%v is kept, %v", old, err)
+			}
+			failed = append(failed, err.Error())
+			continue
+		}
+		if ok {
+			old.close()
+			log.Printf("%v replaced by %v", old, t)
+		} else {
+			log.Printf("%v created", t)
+		}
+		refl.Tunnels[t.Name] = t
+		refl.serveTunnel(t)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%v", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// dispatch creates a cross connection for client connection clientc accepted on tunnel t, and dispatches it
+// to a worker; target is the destination requested by client on dynamic tunnel; caller must hold workerLock
+func (refl *Reflector) dispatch(clientc net.Conn, t *Tunnel, target string) {
+	w := refl.pickWorker(t)
+	if w == nil {
+		log.Printf("no worker of %v signed on, closing client connection %v", t, clientc.RemoteAddr())
+		go func() {
+			replyFailure(clientc, t.Mode, api.CrossStatus_CROSS_NO_WORKER, fmt.Sprintf("no worker of tunnel %v is signed on", t.Name))
+			clientc.Close()
+		}()
+		return
+	}
+	idle, maxlife := refl.idleTimeout, refl.maxLifetime
+	if t.IdleTimeout > 0 {
+		idle = t.IdleTimeout
+	}
+	if t.MaxLifetime > 0 {
+		maxlife = t.MaxLifetime
+	}
+	//flows of udp tunnel have no end but idle timeout
+	if t.Mode == tunnelModeUDP && idle == 0 {
+		idle = defaultUDPIdleTimeout
+	}
+	newcc := &CrossConnection{
+		ID:          refl.currentCCID,
+		WorkerID:    w.ID,
+		Tunnel:      t.Name,
+		Mode:        t.Mode,
+		Conn1:       clientc,
+		StartTime:   time.Now(),
+		IdleTimeout: idle,
+		MaxLifetime: maxlife,
+	}
+	refl.currentCCID++
+	refl.CrossConnections[newcc.ID] = newcc
+	workreq := &api.CreateWorkerCrossReq{
+		ID:     uint32(newcc.ID),
+		Tunnel: t.Name,
+		Target: t.Target,
+		UDP:    t.Mode == tunnelModeUDP,
+	}
+	if target != "" {
+		workreq.Target, workreq.Dynamic = target, true
+	}
+	//client of tunnel answering on failure or success must not see data before worker reports
+	newcc.waitReport = t.Mode != tunnelModeTCP && t.Mode != tunnelModeUDP
+	//bind to an idle pool data connection if there is any, worker will connect it to server
+	if len(w.idleConns) > 0 {
+		pc := w.idleConns[0]
+		w.idleConns = w.idleConns[1:]
+		newcc.Conn2 = pc.Conn
+		workreq.PoolConnID = pc.ID
+		pairingLatency.Observe(time.Since(newcc.StartTime).Seconds())
+		if !newcc.waitReport {
+			go refl.runCC(newcc)
+		}
+	} else {
+		workreq.Token = newToken()
+		refl.ccTokens[string(workreq.Token)] = newcc.ID
+	}
+	select {
+	case w.createWorkerCrossReqChan <- workreq:
+	default:
+		log.Printf("too many pending requests for worker %v, closing client connection %v", w.ID, clientc.RemoteAddr())
+		refl.closeCC(newcc)
+	}
+}
+
+// NewReflector creates a reflector with reflector settings of cfg, if tlsconf is not nil, both API and worker
+// data connections use TLS, and workers must present a certificate verified by tlsconf.ClientCAs
+func NewReflector(cfg *Config, tlsconf *tls.Config) (*Reflector, error) {
+	tunnels := cfg.reflTunnels()
+	workerListenAddr := fmt.Sprintf("0.0.0.0:%d", cfg.WorkerPort)
+	if len(tunnels) == 0 {
+		return nil, fmt.Errorf("no tunnel specified")
 	}
 	waddr, err := net.ResolveTCPAddr("tcp", workerListenAddr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid worker listen address %v, %w", workerListenAddr, err)
 	}
 	r := new(Reflector)
-	r.toClient, err = net.ListenTCP("tcp", caddr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create client listener %v, %w", clientListenAddr, err)
+	r.Tunnels = make(map[string]*Tunnel)
+	for _, t := range tunnels {
+		if _, ok := r.Tunnels[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate tunnel name %v", t.Name)
+		}
+		if err = t.listen(); err != nil {
+			return nil, err
+		}
+		r.Tunnels[t.Name] = t
+		log.Printf("%v created", t)
 	}
 	r.toWorker, err = net.ListenTCP("tcp", waddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create worker listener %v, %w", workerListenAddr, err)
 	}
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", apiport))
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.APIPort))
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen on API port: %w", err)
 	}
-	s := grpc.NewServer()
-	api.RegisterRProxyAPIServer(s, r)
+	r.workerLock = new(sync.RWMutex)
+	r.CrossConnections = make(map[int]*CrossConnection)
+	r.Workers = make(map[string]*RemoteWorker)
+	r.ccTokens = make(map[string]int)
+
+	r.tlsConf = tlsconf
+	r.hbInterval = cfg.HBInterval
+	r.hbMiss = int(cfg.HBMiss)
+	if r.hbInterval > 0 {
+		go r.monitorWorkers()
+	}
+	r.pairTimeout = cfg.PairTimeout
+	r.idleTimeout = cfg.IdleTimeout
+	r.maxLifetime = cfg.MaxLifetime
+	r.acl = cfg.ACL
+	if r.pairTimeout > 0 {
+		go r.reapUnpaired()
+	}
+	r.secret = cfg.Secret
+	r.adminSecret = cfg.AdminSecret
+	if r.secret == "" {
+		log.Print("no secret specified, any worker could sign on")
+	}
+	var opts []grpc.ServerOption
+	if tlsconf != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsconf)))
+	}
+	r.grpcServer = grpc.NewServer(opts...)
+	api.RegisterRProxyAPIServer(r.grpcServer, r)
 	log.Printf("API listening at %v", lis.Addr())
 	go func() {
-		if err := s.Serve(lis); err != nil {
+		if err := r.grpcServer.Serve(lis); err != nil {
 			log.Fatalf("failed to serve: %v", err)
 		}
 	}()
-
-	r.workerLock = new(sync.RWMutex)
-	r.fromWorkerConnQLock = new(sync.RWMutex)
-	r.WorkerAddr = nil
-	r.createWorkerCrossReqChan = make(chan *api.CreateWorkerCrossReq, createWorkerCrossReqChanDepth)
-	r.fromWorkerConnQ = make(map[int]*net.TCPConn)
-
 	return r, nil
 }
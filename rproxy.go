@@ -2,12 +2,12 @@ package main
 
 import (
 	"context"
-	"flag"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
-	"rproxy/api"
+	"os"
 	"runtime"
 	"time"
 
@@ -24,52 +24,89 @@ const (
 )
 
 func main() {
-	lcport := flag.Uint("clport", defaultToClientListenPort, "http client facing listen port")
-	lwport := flag.Uint("wlport", defaultToWOrkerListenPort, "worker facing listen port")
-	apiport := flag.Uint("apiport", defaultReflAPIListenPort, "reflector API listen port")
-	role := flag.String("role", workerRole, "role")
-	refladdr := flag.String("refl", "", "reflector tcp address")
-	reflapiaddr := flag.String("reflapi", "", "reflector api tcp address")
-	svraddr := flag.String("svr", "", "server tcp address")
-	proxyPort := flag.Uint("proxyport", defaultProxyPort, "http proxy listen port")
-	localProxy := flag.Bool("localproxy", true, "use local http proxy")
-	profiling := flag.Bool("p", false, "enable profiling")
-	flag.Parse()
-	if *profiling {
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		if err := runCtl(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Profiling {
 		runtime.SetBlockProfileRate(1000000000)
 		go func() {
 			log.Println(http.ListenAndServe("0.0.0.0:6060", nil))
 		}()
 
 	}
-	switch *role {
+	if cfg.Metrics != "" {
+		go serveMetrics(cfg.Metrics)
+	}
+	var tlsConf *tls.Config
+	if cfg.TLSCert != "" || cfg.TLSKey != "" || cfg.TLSCA != "" {
+		tlsConf, err = loadTLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSCA, cfg.Role == reflRole)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tlsConf.ServerName = cfg.TLSName
+	}
+	switch cfg.Role {
 	default:
-		log.Fatalf("invalid role %v", *role)
+		log.Fatalf("invalid role %v", cfg.Role)
 	case reflRole:
-		refl, err := NewReflector(fmt.Sprintf("0.0.0.0:%d", *lcport),
-			fmt.Sprintf("0.0.0.0:%d", *lwport), int(*apiport))
+		refl, err := NewReflector(cfg, tlsConf)
 		if err != nil {
 			log.Fatal(err)
 		}
 		go refl.ListenForWorker()
 		refl.ListenForClient()
+		sig := waitForSignal(func() {
+			newcfg, err := loadConfig(os.Args[1:])
+			if err != nil {
+				log.Printf("failed to reload config, %v", err)
+				return
+			}
+			if err = refl.Reload(newcfg.reflTunnels(), newcfg.ACL); err != nil {
+				log.Printf("failed to reload tunnels and ACL, %v", err)
+			}
+		})
+		log.Printf("got %v, shutting down", sig)
+		refl.Shutdown(cfg.DrainTimeout)
 	case workerRole:
-		if *localProxy {
+		if cfg.LocalProxy {
 			proxy := goproxy.NewProxyHttpServer()
 			proxy.Verbose = true
-			*svraddr = fmt.Sprintf("127.0.0.1:%d", *proxyPort)
+			cfg.Server = fmt.Sprintf("127.0.0.1:%d", cfg.ProxyPort)
 			go func() {
-				log.Fatal(http.ListenAndServe(*svraddr, proxy))
+				log.Fatal(http.ListenAndServe(cfg.Server, proxy))
 			}()
 			log.Print("starting local proxy server")
 			time.Sleep(3 * time.Second)
 		}
-		worker, err := NewWorker(context.Background(), *reflapiaddr, *refladdr, *svraddr)
+		if cfg.ID == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Fatalf("failed to get hostname as worker ID, %v", err)
+			}
+			cfg.ID = hostname
+		}
+		worker, err := NewWorker(cfg, tlsConf)
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer worker.clnt.Signoff(context.Background(), &api.Empty{})
-		go worker.reportToRefl()
-		worker.listenForCreateReq()
+		ctx, cancel := context.WithCancel(context.Background())
+		go worker.Run(ctx)
+		sig := waitForSignal(func() {
+			newcfg, err := loadConfig(os.Args[1:])
+			if err != nil {
+				log.Printf("failed to reload config, %v", err)
+				return
+			}
+			worker.Reload(newcfg)
+		})
+		log.Printf("got %v, shutting down", sig)
+		worker.Shutdown(cancel, cfg.DrainTimeout)
 	}
 }
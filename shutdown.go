@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultDrainTimeout = 30 * time.Second
+	drainPollInterval   = 100 * time.Millisecond
+	grpcStopTimeout     = 5 * time.Second
+	signoffTimeout      = 5 * time.Second
+)
+
+// waitForSignal blocks until SIGINT or SIGTERM is received, reload is called on each SIGHUP
+func waitForSignal(reload func()) os.Signal {
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigch)
+	for {
+		sig := <-sigch
+		if sig != syscall.SIGHUP {
+			return sig
+		}
+		log.Print("got SIGHUP, reloading config")
+		reload()
+	}
+}
+
+// drain waits up to timeout until count returns 0, returns the last count
+func drain(count func() int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		n := count()
+		if n == 0 || !time.Now().Before(deadline) {
+			return n
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// isClosing returns true if reflector is shutting down
+func (refl *Reflector) isClosing() bool {
+	return atomic.LoadInt32(&refl.closing) == 1
+}
+
+// Shutdown stops accepting new clients, waits up to draintimeout for active cross connections to finish and
+// closes the rest, then removes all workers and stops the API server
+func (refl *Reflector) Shutdown(draintimeout time.Duration) {
+	atomic.StoreInt32(&refl.closing, 1)
+	refl.workerLock.RLock()
+	for _, t := range refl.Tunnels {
+		t.close()
+	}
+	refl.workerLock.RUnlock()
+	log.Printf("stopped accepting clients, waiting up to %v for cross connections to finish", draintimeout)
+	n := drain(func() int {
+		refl.workerLock.RLock()
+		defer refl.workerLock.RUnlock()
+		return len(refl.CrossConnections)
+	}, draintimeout)
+	refl.workerLock.Lock()
+	if n > 0 {
+		log.Printf("closing %d remaining cross connections", n)
+	}
+	for _, cc := range refl.CrossConnections {
+		cc.setCloseReason("reflector shutdown")
+		refl.closeCC(cc)
+	}
+	refl.workerLock.Unlock()
+	refl.toWorker.Close()
+	stopped := make(chan struct{})
+	go func() {
+		refl.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	//ending CreateWorkerCross streams makes workers close their other streams
+	refl.workerLock.Lock()
+	for id := range refl.Workers {
+		refl.removeWorker(id)
+	}
+	refl.workerLock.Unlock()
+	select {
+	case <-stopped:
+	case <-time.After(grpcStopTimeout):
+		log.Print("API server doesn't stop gracefully, stopping it")
+		refl.grpcServer.Stop()
+	}
+	log.Print("reflector stopped")
+}
+
+// isStopping returns true if worker is shutting down
+func (w *Worker) isStopping() bool {
+	return atomic.LoadInt32(&w.stopping) == 1
+}
+
+// Shutdown signs off from reflector, stops the session by cancel, waits up to draintimeout for active cross
+// connections to finish and closes the rest
+func (w *Worker) Shutdown(cancel context.CancelFunc, draintimeout time.Duration) {
+	atomic.StoreInt32(&w.stopping, 1)
+	ctx, cancelSignoff := context.WithTimeout(context.Background(), signoffTimeout)
+	if err := w.Signoff(ctx); err != nil {
+		log.Printf("failed to sign off from reflector, %v", err)
+	}
+	cancelSignoff()
+	cancel()
+	log.Printf("waiting up to %v for cross connections to finish", draintimeout)
+	n := drain(func() int {
+		w.CCLock.RLock()
+		defer w.CCLock.RUnlock()
+		return len(w.CrossConnections)
+	}, draintimeout)
+	if n > 0 {
+		log.Printf("closing %d remaining cross connections", n)
+	}
+	w.CCLock.Lock()
+	for _, cc := range w.CrossConnections {
+		cc.setCloseReason("worker shutdown")
+		cc.Conn1.Close()
+		cc.Conn2.Close()
+	}
+	w.CCLock.Unlock()
+	log.Print("worker stopped")
+}
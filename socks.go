@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"rproxy/api"
+	"strconv"
+)
+
+// SOCKS5 protocol constants, see RFC 1928 and RFC 1929
+const (
+	socks5Version          = 5
+	socks5AuthNone         = 0
+	socks5AuthPassword     = 2
+	socks5AuthNoAcceptable = 0xff
+	socks5PasswordVersion  = 1
+	socks5CmdConnect       = 1
+	socks5AtypIPv4         = 1
+	socks5AtypDomain       = 3
+	socks5AtypIPv6         = 4
+
+	socks5RepSucceeded          = 0
+	socks5RepGeneralFailure     = 1
+	socks5RepNotAllowed         = 2
+	socks5RepNetworkUnreachable = 3
+	socks5RepHostUnreachable    = 4
+	socks5RepConnectionRefused  = 5
+	socks5RepTTLExpired         = 6
+	socks5RepCmdNotSupported    = 7
+	socks5RepAtypNotSupported   = 8
+)
+
+// socks5Handshake negotiates with SOCKS5 client of conn, if user is not empty, client must authenticate with
+// user and pass; it returns the destination of CONNECT request, the reply to the request is not sent
+func socks5Handshake(conn net.Conn, user, pass string) (string, error) {
+	buf := make([]byte, 256)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", fmt.Errorf("failed to read socks greeting, %w", err)
+	}
+	if buf[0] != socks5Version {
+		return "", fmt.Errorf("unsupported socks version %d", buf[0])
+	}
+	methods := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", fmt.Errorf("failed to read socks auth methods, %w", err)
+	}
+	method := byte(socks5AuthNone)
+	if user != "" {
+		method = socks5AuthPassword
+	}
+	offered := false
+	for _, m := range methods {
+		if m == method {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		conn.Write([]byte{socks5Version, socks5AuthNoAcceptable})
+		return "", fmt.Errorf("socks client doesn't offer auth method %d", method)
+	}
+	if _, err := conn.Write([]byte{socks5Version, method}); err != nil {
+		return "", err
+	}
+	if method == socks5AuthPassword {
+		if err := socks5Authenticate(conn, user, pass); err != nil {
+			return "", err
+		}
+	}
+	//request
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return "", fmt.Errorf("failed to read socks request, %w", err)
+	}
+	if buf[0] != socks5Version {
+		return "", fmt.Errorf("unsupported socks version %d in request", buf[0])
+	}
+	if buf[1] != socks5CmdConnect {
+		socks5Reply(conn, socks5RepCmdNotSupported)
+		return "", fmt.Errorf("unsupported socks command %d", buf[1])
+	}
+	var host string
+	switch buf[3] {
+	case socks5AtypIPv4:
+		if _, err := io.ReadFull(conn, buf[:net.IPv4len]); err != nil {
+			return "", err
+		}
+		host = net.IP(buf[:net.IPv4len]).String()
+	case socks5AtypIPv6:
+		if _, err := io.ReadFull(conn, buf[:net.IPv6len]); err != nil {
+			return "", err
+		}
+		host = net.IP(buf[:net.IPv6len]).String()
+	case socks5AtypDomain:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return "", err
+		}
+		l := int(buf[0])
+		if _, err := io.ReadFull(conn, buf[:l]); err != nil {
+			return "", err
+		}
+		host = string(buf[:l])
+	default:
+		socks5Reply(conn, socks5RepAtypNotSupported)
+		return "", fmt.Errorf("unsupported socks address type %d", buf[3])
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	port := binary.BigEndian.Uint16(buf[:2])
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// socks5Authenticate does username/password authentication of RFC 1929
+func socks5Authenticate(conn net.Conn, user, pass string) error {
+	buf := make([]byte, 256)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return fmt.Errorf("failed to read socks auth request, %w", err)
+	}
+	if buf[0] != socks5PasswordVersion {
+		return fmt.Errorf("unsupported socks auth version %d", buf[0])
+	}
+	ulen := int(buf[1])
+	if _, err := io.ReadFull(conn, buf[:ulen+1]); err != nil {
+		return err
+	}
+	u := string(buf[:ulen])
+	plen := int(buf[ulen])
+	if _, err := io.ReadFull(conn, buf[:plen]); err != nil {
+		return err
+	}
+	p := string(buf[:plen])
+	ok := subtle.ConstantTimeCompare([]byte(u), []byte(user))&subtle.ConstantTimeCompare([]byte(p), []byte(pass)) == 1
+	if !ok {
+		conn.Write([]byte{socks5PasswordVersion, 1})
+		return fmt.Errorf("invalid socks username or password of user %v", u)
+	}
+	_, err := conn.Write([]byte{socks5PasswordVersion, 0})
+	return err
+}
+
+// socks5Reply sends reply rep to the request, bound address is always 0.0.0.0:0 since the connection to
+// destination is made by worker
+func socks5Reply(conn net.Conn, rep byte) error {
+	_, err := conn.Write([]byte{socks5Version, rep, 0, socks5AtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// socks5ReplyCode returns SOCKS5 reply code of failure status st reported by worker
+func socks5ReplyCode(st api.CrossStatus) byte {
+	switch st {
+	case api.CrossStatus_CROSS_DEST_NOT_ALLOWED:
+		return socks5RepNotAllowed
+	case api.CrossStatus_CROSS_SERVER_REFUSED:
+		return socks5RepConnectionRefused
+	case api.CrossStatus_CROSS_SERVER_HOST_UNREACHABLE:
+		return socks5RepHostUnreachable
+	case api.CrossStatus_CROSS_SERVER_NET_UNREACHABLE:
+		return socks5RepNetworkUnreachable
+	case api.CrossStatus_CROSS_SERVER_TIMEOUT:
+		return socks5RepTTLExpired
+	}
+	return socks5RepGeneralFailure
+}
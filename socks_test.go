@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const handshakeTestTimeout = 200 * time.Millisecond
+
+// runHandshake runs handshake on the server end of a pipe while client sends input,
+// returns the result and everything sent back to client; handshake waiting for more than input times out
+func runHandshake(input []byte, handshake func(net.Conn) (string, error)) (string, error, []byte) {
+	srv, cli := net.Pipe()
+	defer cli.Close()
+	srv.SetDeadline(time.Now().Add(handshakeTestTimeout))
+	go cli.Write(input)
+	replyc := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(cli)
+		replyc <- b
+	}()
+	target, err := handshake(srv)
+	srv.Close()
+	return target, err, <-replyc
+}
+
+func socks5Request(cmd, atyp byte, addr []byte, port uint16) []byte {
+	b := []byte{socks5Version, cmd, 0, atyp}
+	if atyp == socks5AtypDomain {
+		b = append(b, byte(len(addr)))
+	}
+	b = append(b, addr...)
+	return append(b, byte(port>>8), byte(port))
+}
+
+func socks5Auth(user, pass string) []byte {
+	b := []byte{socks5PasswordVersion, byte(len(user))}
+	b = append(b, user...)
+	b = append(b, byte(len(pass)))
+	return append(b, pass...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestSOCKS5Handshake(t *testing.T) {
+	allMethods := make([]byte, 255)
+	for i := range allMethods {
+		allMethods[i] = byte(i)
+	}
+	longName := strings.Repeat("a", 251) + ".com"
+	longUser, longPass := strings.Repeat("u", 255), strings.Repeat("p", 255)
+	noAuth := []byte{socks5Version, 1, socks5AuthNone}
+	pwAuth := []byte{socks5Version, 1, socks5AuthPassword}
+	cases := []struct {
+		name       string
+		user, pass string
+		input      []byte
+		target     string //empty means handshake fails
+		reply      []byte
+	}{
+		{
+			name:   "ipv4",
+			input:  concat(noAuth, socks5Request(socks5CmdConnect, socks5AtypIPv4, []byte{10, 0, 0, 1}, 80)),
+			target: "10.0.0.1:80",
+			reply:  []byte{socks5Version, socks5AuthNone},
+		},
+		{
+			name:   "ipv6",
+			input:  concat(noAuth, socks5Request(socks5CmdConnect, socks5AtypIPv6, net.ParseIP("2001:db8::1"), 22)),
+			target: "[2001:db8::1]:22",
+			reply:  []byte{socks5Version, socks5AuthNone},
+		},
+		{
+			name:   "max length domain",
+			input:  concat(noAuth, socks5Request(socks5CmdConnect, socks5AtypDomain, []byte(longName), 443)),
+			target: longName + ":443",
+			reply:  []byte{socks5Version, socks5AuthNone},
+		},
+		{
+			name:   "max number of methods",
+			input:  concat([]byte{socks5Version, 255}, allMethods, socks5Request(socks5CmdConnect, socks5AtypIPv4, []byte{10, 0, 0, 1}, 80)),
+			target: "10.0.0.1:80",
+			reply:  []byte{socks5Version, socks5AuthNone},
+		},
+		{
+			name:  "max number of methods without acceptable one",
+			user:  "u",
+			pass:  "p",
+			input: concat([]byte{socks5Version, 255}, allMethods[:2], allMethods[3:], []byte{0}),
+			reply: []byte{socks5Version, socks5AuthNoAcceptable},
+		},
+		{
+			name:   "max length credentials",
+			user:   longUser,
+			pass:   longPass,
+			input:  concat(pwAuth, socks5Auth(longUser, longPass), socks5Request(socks5CmdConnect, socks5AtypIPv4, []byte{10, 0, 0, 1}, 80)),
+			target: "10.0.0.1:80",
+			reply:  []byte{socks5Version, socks5AuthPassword, socks5PasswordVersion, 0},
+		},
+		{
+			name:  "wrong password",
+			user:  "u",
+			pass:  "p",
+			input: concat(pwAuth, socks5Auth("u", "x")),
+			reply: []byte{socks5Version, socks5AuthPassword, socks5PasswordVersion, 1},
+		},
+		{
+			name:  "no password offered",
+			user:  "u",
+			pass:  "p",
+			input: noAuth,
+			reply: []byte{socks5Version, socks5AuthNoAcceptable},
+		},
+		{
+			name:  "unsupported command",
+			input: concat(noAuth, socks5Request(2, socks5AtypIPv4, []byte{10, 0, 0, 1}, 80)),
+			reply: []byte{socks5Version, socks5AuthNone, socks5Version, socks5RepCmdNotSupported, 0, socks5AtypIPv4, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:  "unsupported address type",
+			input: concat(noAuth, []byte{socks5Version, socks5CmdConnect, 0, 5}),
+			reply: []byte{socks5Version, socks5AuthNone, socks5Version, socks5RepAtypNotSupported, 0, socks5AtypIPv4, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:  "unsupported version",
+			input: []byte{4, 1, 0, 80, 10, 0, 0, 1, 0},
+		},
+		{
+			name:  "truncated request",
+			input: concat(noAuth, []byte{socks5Version, socks5CmdConnect, 0, socks5AtypDomain, 10, 'a'}),
+			reply: []byte{socks5Version, socks5AuthNone},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			target, err, reply := runHandshake(c.input, func(conn net.Conn) (string, error) {
+				return socks5Handshake(conn, c.user, c.pass)
+			})
+			if c.target == "" && err == nil {
+				t.Errorf("handshake succeeded with target %v, expect failure", target)
+			}
+			if c.target != "" && (err != nil || target != c.target) {
+				t.Errorf("got target %q and error %v, expect %q", target, err, c.target)
+			}
+			if !bytes.Equal(reply, c.reply) {
+				t.Errorf("got reply %v, expect %v", reply, c.reply)
+			}
+		})
+	}
+}
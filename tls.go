@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+)
+
+// loadTLSConfig returns TLS config using certFile/keyFile as own certificate, and certificates in caFile to
+// verify peer; if server is true, client must present a certificate signed by caFile (mutual authentication)
+func loadTLSConfig(certFile, keyFile, caFile string, server bool) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, fmt.Errorf("TLS certificate, key and CA must be all specified")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate %v, %w", certFile, err)
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS CA %v, %w", caFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificate found in TLS CA %v", caFile)
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if server {
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		conf.RootCAs = pool
+	}
+	return conf, nil
+}
+
+// clientTLSConfig returns a copy of conf with ServerName set to host of addr if it is not specified
+func clientTLSConfig(conf *tls.Config, addr string) *tls.Config {
+	r := conf.Clone()
+	if r.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			r.ServerName = host
+		}
+	}
+	return r
+}
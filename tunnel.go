@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTunnelName = "default"
+	tunnelModeTCP     = "tcp"
+	tunnelModeHTTP    = "http"
+	tunnelModeSOCKS5  = "socks5"
+	tunnelModeConnect = "connect"
+	tunnelModeUDP     = "udp"
+)
+
+// Tunnel is a named client facing listener on reflector,
+// client connections accepted by it are forwarded to Target by worker
+type Tunnel struct {
+	Name        string           `yaml:"name"`
+	ListenAddr  string           `yaml:"listen"`
+	Target      string           `yaml:"target"`      //if empty, worker decides the server address
+	Mode        string           `yaml:"mode"`        //protocol of client connections, tcp, http, socks5, connect or udp
+	Username    string           `yaml:"username"`    //socks5 and connect only, if not empty, clients must authenticate with it and Password
+	Password    string           `yaml:"password"`    //socks5 and connect only
+	IdleTimeout time.Duration    `yaml:"idletimeout"` //overrides reflector's idle timeout of cross connections if not 0
+	MaxLifetime time.Duration    `yaml:"maxlifetime"` //overrides reflector's max lifetime of cross connections if not 0
+	Workers     []string         `yaml:"workers"`     //IDs of workers able to reach the target, empty means any worker
+	ACL         `yaml:",inline"` //checked in addition to reflector's ACL
+	listener    *net.TCPListener
+	udpConn     *net.UDPConn //listener of udp tunnel
+}
+
+func (t Tunnel) String() string {
+	return fmt.Sprintf("%v tunnel %v (%v -> %v)", t.Mode, t.Name, t.ListenAddr, t.Target)
+}
+
+// dynamic returns true if destination of each client connection on t is requested by client
+func (t *Tunnel) dynamic() bool {
+	return t.Mode == tunnelModeSOCKS5 || t.Mode == tunnelModeConnect
+}
+
+// servedBy returns true if worker id is eligible for client connections of t
+func (t *Tunnel) servedBy(id string) bool {
+	if len(t.Workers) == 0 {
+		return true
+	}
+	for _, w := range t.Workers {
+		if w == id {
+			return true
+		}
+	}
+	return false
+}
+
+// network returns network of client facing listener of t
+func (t *Tunnel) network() string {
+	if t.Mode == tunnelModeUDP {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// listen creates the client facing listener of t
+func (t *Tunnel) listen() error {
+	if t.Mode == tunnelModeUDP {
+		caddr, err := net.ResolveUDPAddr("udp", t.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("invalid client listen address %v, %w", t.ListenAddr, err)
+		}
+		t.udpConn, err = net.ListenUDP("udp", caddr)
+		if err != nil {
+			return fmt.Errorf("failed to create client listener %v, %w", t.ListenAddr, err)
+		}
+		return nil
+	}
+	caddr, err := net.ResolveTCPAddr("tcp", t.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid client listen address %v, %w", t.ListenAddr, err)
+	}
+	t.listener, err = net.ListenTCP("tcp", caddr)
+	if err != nil {
+		return fmt.Errorf("failed to create client listener %v, %w", t.ListenAddr, err)
+	}
+	return nil
+}
+
+// close closes the client facing listener of t
+func (t *Tunnel) close() error {
+	if t.udpConn != nil {
+		return t.udpConn.Close()
+	}
+	return t.listener.Close()
+}
+
+// ParseTunnel parses tunnel spec in format of name=listenaddr[,target[,mode]], default mode is tcp
+func ParseTunnel(s string) (*Tunnel, error) {
+	fields := strings.SplitN(s, "=", 2)
+	if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+		return nil, fmt.Errorf("invalid tunnel %v, expect name=listenaddr[,target[,mode]]", s)
+	}
+	name := fields[0]
+	t := &Tunnel{Name: name, Mode: tunnelModeTCP}
+	addrs := strings.SplitN(fields[1], ",", 3)
+	t.ListenAddr = addrs[0]
+	if len(addrs) > 1 {
+		t.Target = addrs[1]
+	}
+	if len(addrs) > 2 {
+		t.Mode = addrs[2]
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid tunnel %v, %w", s, err)
+	}
+	return t, nil
+}
+
+// validate checks t and sets default mode if it is empty, returned error starts with the offending key
+func (t *Tunnel) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("name: empty tunnel name")
+	}
+	if t.Mode == "" {
+		t.Mode = tunnelModeTCP
+	}
+	switch t.Mode {
+	case tunnelModeTCP, tunnelModeHTTP, tunnelModeSOCKS5, tunnelModeConnect, tunnelModeUDP:
+	default:
+		return fmt.Errorf("mode: invalid mode %v of tunnel %v, expect %v, %v, %v, %v or %v", t.Mode, t.Name,
+			tunnelModeTCP, tunnelModeHTTP, tunnelModeSOCKS5, tunnelModeConnect, tunnelModeUDP)
+	}
+	if _, err := net.ResolveTCPAddr("tcp", t.ListenAddr); err != nil {
+		return fmt.Errorf("listen: invalid listen address of tunnel %v, %w", t.Name, err)
+	}
+	if t.Target != "" {
+		if t.dynamic() {
+			return fmt.Errorf("target: %v tunnel %v takes destination from client, target must be empty", t.Mode, t.Name)
+		}
+		if _, _, err := net.SplitHostPort(t.Target); err != nil {
+			return fmt.Errorf("target: invalid target of tunnel %v, %w", t.Name, err)
+		}
+	}
+	if !t.dynamic() && (t.Username != "" || t.Password != "") {
+		return fmt.Errorf("username: only %v and %v tunnels support authentication, tunnel %v is %v",
+			tunnelModeSOCKS5, tunnelModeConnect, t.Name, t.Mode)
+	}
+	if t.Username == "" && t.Password != "" {
+		return fmt.Errorf("username: empty username with password of tunnel %v", t.Name)
+	}
+	if len(t.Username) > 255 || len(t.Password) > 255 {
+		return fmt.Errorf("username: username and password of tunnel %v must not exceed 255 bytes", t.Name)
+	}
+	for _, w := range t.Workers {
+		if w == "" {
+			return fmt.Errorf("workers: empty worker ID of tunnel %v", t.Name)
+		}
+	}
+	if t.IdleTimeout < 0 {
+		return fmt.Errorf("idletimeout: negative duration of tunnel %v", t.Name)
+	}
+	if t.MaxLifetime < 0 {
+		return fmt.Errorf("maxlifetime: negative duration of tunnel %v", t.Name)
+	}
+	return nil
+}
+
+// tunnelList implements flag.Value, used for repeatable reflector -tunnel flag
+type tunnelList []*Tunnel
+
+func (tl *tunnelList) String() string {
+	if tl == nil {
+		return ""
+	}
+	var r []string
+	for _, t := range *tl {
+		r = append(r, t.String())
+	}
+	return strings.Join(r, ";")
+}
+
+func (tl *tunnelList) Set(s string) error {
+	t, err := ParseTunnel(s)
+	if err != nil {
+		return err
+	}
+	//replace the tunnel with same name, e.g. one loaded from config file
+	for i, old := range *tl {
+		if old.Name == t.Name {
+			(*tl)[i] = t
+			return nil
+		}
+	}
+	*tl = append(*tl, t)
+	return nil
+}
+
+// targetMap implements flag.Value, used for repeatable worker -target flag;
+// key is tunnel name, value is server address
+type targetMap map[string]string
+
+func (tm targetMap) String() string {
+	var r []string
+	for name, target := range tm {
+		r = append(r, name+"="+target)
+	}
+	return strings.Join(r, ";")
+}
+
+func (tm targetMap) Set(s string) error {
+	fields := strings.SplitN(s, "=", 2)
+	if len(fields) != 2 || fields[0] == "" {
+		return fmt.Errorf("invalid target %v, expect name=server", s)
+	}
+	name, target := fields[0], fields[1]
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return fmt.Errorf("invalid target of tunnel %v, %w", name, err)
+	}
+	tm[name] = target
+	return nil
+}
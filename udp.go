@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	maxDatagramSize       = 65535
+	udpFlowQueueDepth     = 64
+	defaultUDPIdleTimeout = time.Minute
+)
+
+// datagramConn turns packet connection Conn into a byte stream of length-framed datagrams, each datagram is
+// prefixed with its length as a 2-byte big-endian integer; it is used on both sides of cross connections of
+// UDP tunnels so that datagram boundaries survive the data connection between worker and reflector
+type datagramConn struct {
+	net.Conn
+	rbuf []byte //framed datagram not returned by Read yet
+	wbuf []byte //partial frame written so far
+	pkt  []byte
+}
+
+func newDatagramConn(conn net.Conn) *datagramConn {
+	return &datagramConn{
+		Conn: conn,
+		pkt:  make([]byte, maxDatagramSize),
+	}
+}
+
+// Read reads a datagram from the packet connection if there is no pending one, and returns its frame
+func (dc *datagramConn) Read(p []byte) (int, error) {
+	if len(dc.rbuf) == 0 {
+		n, err := dc.Conn.Read(dc.pkt)
+		if err != nil {
+			return 0, err
+		}
+		dc.rbuf = make([]byte, 2+n)
+		binary.BigEndian.PutUint16(dc.rbuf, uint16(n))
+		copy(dc.rbuf[2:], dc.pkt[:n])
+	}
+	n := copy(p, dc.rbuf)
+	dc.rbuf = dc.rbuf[n:]
+	return n, nil
+}
+
+// Write collects frames from p, and sends each complete one as a datagram over the packet connection
+func (dc *datagramConn) Write(p []byte) (int, error) {
+	dc.wbuf = append(dc.wbuf, p...)
+	for len(dc.wbuf) >= 2 {
+		l := 2 + int(binary.BigEndian.Uint16(dc.wbuf))
+		if len(dc.wbuf) < l {
+			break
+		}
+		if _, err := dc.Conn.Write(dc.wbuf[2:l]); err != nil {
+			return 0, err
+		}
+		dc.wbuf = dc.wbuf[l:]
+	}
+	if len(dc.wbuf) == 0 {
+		dc.wbuf = nil
+	}
+	return len(p), nil
+}
+
+// udpFlow is a net.Conn of datagrams exchanged with a client address on the listener of a UDP tunnel,
+// datagrams from the client are queued to it by the listener routine
+type udpFlow struct {
+	conn         *net.UDPConn
+	addr         *net.UDPAddr
+	in           chan []byte
+	done         chan struct{}
+	closeOnce    *sync.Once
+	onClose      func()
+	deadlineLock *sync.Mutex
+	readDeadline time.Time
+}
+
+func newUDPFlow(conn *net.UDPConn, addr *net.UDPAddr, onClose func()) *udpFlow {
+	return &udpFlow{
+		conn:         conn,
+		addr:         addr,
+		in:           make(chan []byte, udpFlowQueueDepth),
+		done:         make(chan struct{}),
+		closeOnce:    new(sync.Once),
+		onClose:      onClose,
+		deadlineLock: new(sync.Mutex),
+	}
+}
+
+// deliver queues datagram b from the client, b is dropped if the queue is full
+func (f *udpFlow) deliver(b []byte) bool {
+	select {
+	case f.in <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *udpFlow) Read(p []byte) (int, error) {
+	f.deadlineLock.Lock()
+	deadline := f.readDeadline
+	f.deadlineLock.Unlock()
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b := <-f.in:
+		return copy(p, b), nil
+	case <-f.done:
+		return 0, io.EOF
+	case <-timeout:
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (f *udpFlow) Write(p []byte) (int, error) {
+	select {
+	case <-f.done:
+		return 0, net.ErrClosed
+	default:
+	}
+	return f.conn.WriteToUDP(p, f.addr)
+}
+
+// Close ends the flow, the listener of the tunnel is left open
+func (f *udpFlow) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.done)
+		f.onClose()
+	})
+	return nil
+}
+
+func (f *udpFlow) LocalAddr() net.Addr  { return f.conn.LocalAddr() }
+func (f *udpFlow) RemoteAddr() net.Addr { return f.addr }
+
+func (f *udpFlow) SetDeadline(t time.Time) error {
+	return f.SetReadDeadline(t)
+}
+
+func (f *udpFlow) SetReadDeadline(t time.Time) error {
+	f.deadlineLock.Lock()
+	defer f.deadlineLock.Unlock()
+	f.readDeadline = t
+	return nil
+}
+
+// SetWriteDeadline does nothing since writing a datagram doesn't block
+func (f *udpFlow) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// listenForUDPClient receives datagrams on listener of UDP tunnel t until it is closed by reload or shutdown;
+// datagrams are grouped into flows by client address, a new flow is dispatched to a worker as a cross connection
+// and ends with it, e.g. when it is idle for the idle timeout
+func (refl *Reflector) listenForUDPClient(t *Tunnel) {
+	ln, name := t.udpConn, t.Name
+	flows := make(map[string]*udpFlow)
+	flowLock := new(sync.Mutex)
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := ln.ReadFromUDP(buf)
+		if err != nil {
+			refl.workerLock.RLock()
+			cur, ok := refl.Tunnels[name]
+			refl.workerLock.RUnlock()
+			if !ok || cur.udpConn != ln || refl.isClosing() {
+				log.Printf("stopped receiving client datagrams on %v", t)
+				return
+			}
+			log.Fatalf("failed to receive client datagram on %v, %v", cur, err)
+		}
+		b := append([]byte(nil), buf[:n]...)
+		key := addr.String()
+		flowLock.Lock()
+		f, ok := flows[key]
+		flowLock.Unlock()
+		if ok {
+			if !f.deliver(b) {
+				log.Printf("queue of client flow from %v on %v is full, dropped a datagram", addr, t)
+			}
+			continue
+		}
+		refl.workerLock.Lock()
+		cur, ok := refl.Tunnels[name]
+		if !ok || cur.udpConn != ln {
+			refl.workerLock.Unlock()
+			log.Printf("stopped receiving client datagrams on %v", t)
+			return
+		}
+		if !refl.acl.Allowed(addr.IP) || !cur.Allowed(addr.IP) {
+			refl.workerLock.Unlock()
+			log.Printf("rejected client datagram from %v on %v by ACL", addr, cur)
+			rejectedClients.WithLabelValues(name).Inc()
+			continue
+		}
+		log.Printf("new client flow from %v on %v", addr, cur)
+		acceptedClients.WithLabelValues(name).Inc()
+		f = newUDPFlow(ln, addr, func() {
+			flowLock.Lock()
+			if flows[key] == f {
+				delete(flows, key)
+			}
+			flowLock.Unlock()
+		})
+		f.deliver(b)
+		flowLock.Lock()
+		flows[key] = f
+		flowLock.Unlock()
+		refl.dispatch(newDatagramConn(f), cur, "")
+		refl.workerLock.Unlock()
+	}
+}
+
+// dialUDPServer connects to UDP server addr, the returned connection carries length-framed datagrams
+func dialUDPServer(addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout("udp", addr, serverDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newDatagramConn(conn), nil
+}
@@ -2,103 +2,539 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net"
 	"rproxy/api"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/hashicorp/yamux"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type Worker struct {
 	clnt              api.RProxyAPIClient
+	ID                string
 	svrAddr, reflAddr string
-	creatCCStream     api.RProxyAPI_CreateWorkerCrossClient
-	reportCCStream    api.RProxyAPI_ReportWorkerCrossClient
+	tlsConf           *tls.Config //TLS config for data connections to refl, nil means plaintext
+	secret            string
+	dataToken         []byte                   //issued by refl in Signon, used on multiplexed and pool data connections
+	sessionID         string                   //issued by refl in Signon, empty means not signed on
+	sessLock          *sync.RWMutex            //protects dataToken, sessionID and sessChanged
+	sessChanged       chan struct{}            //closed and replaced whenever session changes
+	Targets           map[string]string        //key is tunnel name, value is server address
+	allowDest         destList                 //allowlist of targets from reflector, empty means refusing all unless allowAnyDest
+	allowAnyDest      bool                     //allow any target from reflector if allowDest is empty
+	targetLock        *sync.RWMutex            //protects Targets, allowDest and allowAnyDest
 	CrossConnections  map[int]*CrossConnection //conn1 is to refl
 	CCLock            *sync.RWMutex
 	reportChan        chan *api.ReportWorkerCrossReq
+	muxSessions       []*muxSession //multiplexed data connections to refl, empty means not using mux
+	muxLock           *sync.RWMutex
+	nextMux           int
+	poolSize          int
+	idleConns         map[uint32]net.Conn //pre-dialed idle data connections to refl, key is pool connection ID
+	poolLock          *sync.Mutex
+	nextPoolID        uint32
+	poolRefill        chan struct{}
+	rnd               *rand.Rand    //used for reconnect jitter
+	idleTimeout       time.Duration //idle timeout of cross connections, 0 means no timeout
+	maxLifetime       time.Duration //max lifetime of cross connections, 0 means no limit
+	stopping          int32         //set to 1 when shutting down, accessed atomically
 }
 
 const (
-	reportChanDepth = 128
+	reportChanDepth    = 128
+	muxRedialInterval  = 3 * time.Second
+	poolRefillInterval = 3 * time.Second
+	minReconnectDelay  = time.Second
+	maxReconnectDelay  = time.Minute
+	serverDialTimeout  = 10 * time.Second
 )
 
-func NewWorker(ctx context.Context, reflmgmtaddr, refldataaddr, svr string) (*Worker, error) {
-	conn, err := grpc.Dial(reflmgmtaddr, grpc.WithInsecure())
+// NewWorker creates a worker with worker settings of cfg, if tlsconf is not nil, both API and data connections
+// to reflector use TLS
+func NewWorker(cfg *Config, tlsconf *tls.Config) (*Worker, error) {
+	reflmgmtaddr, refldataaddr := cfg.ReflAPI, cfg.Refl
+	creds := grpc.WithInsecure()
+	if tlsconf != nil {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(clientTLSConfig(tlsconf, reflmgmtaddr)))
+	}
+	conn, err := grpc.Dial(reflmgmtaddr, creds)
 	if err != nil {
 		return nil, err
 	}
 	r := new(Worker)
+	if tlsconf != nil {
+		r.tlsConf = clientTLSConfig(tlsconf, refldataaddr)
+	}
 	r.clnt = api.NewRProxyAPIClient(conn)
-	r.svrAddr = svr
+	r.ID = cfg.ID
+	r.svrAddr = cfg.Server
 	r.reflAddr = refldataaddr
-	r.creatCCStream, err = r.clnt.CreateWorkerCross(ctx, &api.Empty{})
+	r.Targets = cfg.Targets
+	r.allowDest = cfg.AllowDest
+	r.allowAnyDest = cfg.AllowAnyDest
+	r.targetLock = new(sync.RWMutex)
+	r.secret = cfg.Secret
+	r.idleTimeout = cfg.IdleTimeout
+	r.maxLifetime = cfg.MaxLifetime
+	r.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	r.sessLock = new(sync.RWMutex)
+	r.sessChanged = make(chan struct{})
+	r.CCLock = new(sync.RWMutex)
+	r.CrossConnections = make(map[int]*CrossConnection)
+	r.reportChan = make(chan *api.ReportWorkerCrossReq, reportChanDepth)
+	r.muxLock = new(sync.RWMutex)
+	//with mux, worker multiplexes all cross connections over long-lived data connections, otherwise it keeps
+	//a pool of idle pre-dialed data connections if pool is not 0
+	r.muxSessions = make([]*muxSession, cfg.Mux)
+	for i := range r.muxSessions {
+		go r.maintainMuxSession(i)
+	}
+	r.poolSize = int(cfg.Pool)
+	r.idleConns = make(map[uint32]net.Conn)
+	r.poolLock = new(sync.Mutex)
+	r.poolRefill = make(chan struct{}, 1)
+	if cfg.Mux == 0 && cfg.Pool > 0 {
+		go r.maintainPool()
+	}
+	log.Printf("worker %v created, with refl api %v,refl data %v and sever %v",
+		r.ID, reflmgmtaddr, refldataaddr, r.svrAddr)
+	return r, nil
+}
+
+// Run signs on to reflector and serves its create requests until ctx is done; whenever the connection to
+// reflector is lost, it signs on again with exponential backoff and jitter, existing cross connections are kept
+func (w *Worker) Run(ctx context.Context) {
+	delay := minReconnectDelay
+	for {
+		start := time.Now()
+		err := w.runSession(ctx)
+		if ctx.Err() != nil || w.isStopping() {
+			return
+		}
+		//the session was up for a while, start over the backoff
+		if time.Since(start) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		d := delay/2 + time.Duration(w.rnd.Int63n(int64(delay/2)+1))
+		log.Printf("lost connection to reflector, %v, reconnecting in %v", err, d)
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// runSession signs on to reflector, and serves until either stream to reflector fails
+func (w *Worker) runSession(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer w.setSession("", nil)
+	signonResp, err := w.clnt.Signon(ctx, &api.SignonReq{WorkerID: w.ID, Secret: w.secret})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create createworker stream, %w", err)
+		return fmt.Errorf("failed to sign on, %w", err)
 	}
-	r.reportCCStream, err = r.clnt.ReportWorkerCross(ctx)
+	w.setSession(signonResp.SessionID, signonResp.DataToken)
+	creatCCStream, err := w.clnt.CreateWorkerCross(ctx, w.workerReq())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create reportworker stream, %w", err)
+		return fmt.Errorf("failed to create createworker stream, %w", err)
 	}
-	_, err = r.clnt.Signon(ctx, &api.Empty{})
+	reportCCStream, err := w.clnt.ReportWorkerCross(w.workerStreamContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign on, %w", err)
+		return fmt.Errorf("failed to create reportworker stream, %w", err)
 	}
-	r.CCLock = new(sync.RWMutex)
-	r.CrossConnections = make(map[int]*CrossConnection)
-	r.reportChan = make(chan *api.ReportWorkerCrossReq, reportChanDepth)
-	log.Printf("worker created, with refl api %v,refl data %v and sever %v",
-		reflmgmtaddr, refldataaddr, svr)
-	return r, nil
+	log.Printf("worker %v signed on to reflector", w.ID)
+	//idle pool data connections of previous session are no longer valid
+	w.flushPool()
+	errch := make(chan error, 3)
+	go func() {
+		errch <- w.reportToRefl(ctx, reportCCStream)
+	}()
+	go func() {
+		errch <- w.heartbeat(ctx, time.Duration(signonResp.HeartbeatInterval)*time.Millisecond,
+			int(signonResp.HeartbeatMiss))
+	}()
+	go func() {
+		errch <- w.listenForCreateReq(creatCCStream)
+	}()
+	return <-errch
+}
+
+func (w *Worker) setSession(sessionID string, dataToken []byte) {
+	w.sessLock.Lock()
+	defer w.sessLock.Unlock()
+	w.sessionID = sessionID
+	w.dataToken = dataToken
+	close(w.sessChanged)
+	w.sessChanged = make(chan struct{})
+}
+
+// waitSignon blocks until worker is signed on
+func (w *Worker) waitSignon() {
+	for {
+		w.sessLock.RLock()
+		sessionID, changed := w.sessionID, w.sessChanged
+		w.sessLock.RUnlock()
+		if sessionID != "" {
+			return
+		}
+		<-changed
+	}
+}
+
+// session returns current session ID and data token, empty session ID means not signed on
+func (w *Worker) session() (string, []byte) {
+	w.sessLock.RLock()
+	defer w.sessLock.RUnlock()
+	return w.sessionID, w.dataToken
+}
+
+func (w *Worker) workerReq() *api.WorkerReq {
+	sessionID, _ := w.session()
+	return &api.WorkerReq{WorkerID: w.ID, SessionID: sessionID}
+}
+
+// Signoff signs off from reflector
+func (w *Worker) Signoff(ctx context.Context) error {
+	_, err := w.clnt.Signoff(ctx, w.workerReq())
+	return err
 }
-func (w *Worker) reportToRefl() {
+
+func (w *Worker) reportToRefl(ctx context.Context, stream api.RProxyAPI_ReportWorkerCrossClient) error {
 	defer log.Print("report worker routine ended")
-	var err error
 	for {
-		report := <-w.reportChan
-		err = w.reportCCStream.Send(report)
-		if err != nil {
-			log.Printf("can't report to reflector, %v", err)
+		select {
+		case report := <-w.reportChan:
+			if err := stream.Send(report); err != nil {
+				return fmt.Errorf("can't report to reflector, %w", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+	}
+}
 
+// serverAddr returns the server address for req, worker's own target of the tunnel takes precedence,
+// then the target specified by reflector, svrAddr is used if neither is set; a dynamic target requested by
+// client is always used. A target from reflector, dynamic or not, is not trusted: it must be allowed by allowDest,
+// or allowDest is empty and allowAnyDest is set, the returned address is the one to connect to
+func (w *Worker) serverAddr(req *api.CreateWorkerCrossReq) (string, error) {
+	w.targetLock.RLock()
+	target, ok := w.Targets[req.Tunnel]
+	allowdest, allowany := w.allowDest, w.allowAnyDest
+	w.targetLock.RUnlock()
+	if !req.Dynamic {
+		if ok {
+			return target, nil
+		}
+		if req.Target == "" {
+			return w.svrAddr, nil
+		}
 	}
+	if len(allowdest) == 0 && !allowany {
+		return "", fmt.Errorf("destination %v from reflector is not allowed, no allowdest is configured", req.Target)
+	}
+	return allowdest.check(req.Target)
+}
+
+// Reload replaces server addresses of tunnels and destination allowlist with those of cfg,
+// existing cross connections are not affected
+func (w *Worker) Reload(cfg *Config) {
+	targets, allowdest := cfg.Targets, cfg.AllowDest
+	w.targetLock.Lock()
+	defer w.targetLock.Unlock()
+	w.Targets = targets
+	w.allowDest = allowdest
+	w.allowAnyDest = cfg.AllowAnyDest
+	log.Printf("worker targets reloaded, %d tunnel targets, %d allowed destinations", len(targets), len(allowdest))
 }
 
-func (w *Worker) listenForCreateReq() {
+func (w *Worker) listenForCreateReq(stream api.RProxyAPI_CreateWorkerCrossClient) error {
 	defer log.Print("listen for create worker req routine ended")
 	for {
-		req, err := w.creatCCStream.Recv()
+		req, err := stream.Recv()
 		if err != nil {
-			log.Fatalf("faild to recv from create worker stream, %v", err)
+			return fmt.Errorf("faild to recv from create worker stream, %w", err)
 		}
-		svrconn, err := net.Dial("tcp", w.svrAddr)
-		if err != nil {
-			if err != nil {
-				log.Printf("can't connect to proxy server %v, %v", w.svrAddr, err)
-				continue
-			}
+		//dialing server may take a while, don't hold up other requests
+		go w.createCross(req)
+	}
+}
+
+// createCross connects to the server of req and creates the cross connection, the result is reported to reflector
+func (w *Worker) createCross(req *api.CreateWorkerCrossReq) {
+	var poolconn net.Conn
+	if req.PoolConnID != 0 {
+		poolconn = w.takePoolConn(req.PoolConnID)
+		if poolconn == nil {
+			log.Printf("pool data connection %d for crossconnection %d not found", req.PoolConnID, req.ID)
+			w.reportFailure(req.ID, api.CrossStatus_CROSS_POOL_CONN_NOT_FOUND,
+				fmt.Errorf("pool data connection %d not found", req.PoolConnID))
+			return
 		}
-		reflconn, err := net.Dial("tcp", w.reflAddr)
-		if err != nil {
-			if err != nil {
-				log.Printf("can't connect to reflector %v, %v", w.reflAddr, err)
-				continue
-			}
+	}
+	svraddr, err := w.serverAddr(req)
+	if err != nil {
+		log.Printf("refused crossconnection %d of tunnel %v, %v", req.ID, req.Tunnel, err)
+		w.reportFailure(req.ID, api.CrossStatus_CROSS_DEST_NOT_ALLOWED, err)
+		if poolconn != nil {
+			poolconn.Close()
+		}
+		return
+	}
+	var svrconn net.Conn
+	if req.UDP {
+		svrconn, err = dialUDPServer(svraddr)
+	} else {
+		svrconn, err = net.DialTimeout("tcp", svraddr, serverDialTimeout)
+	}
+	if err != nil {
+		log.Printf("can't connect to server %v of tunnel %v, %v", svraddr, req.Tunnel, err)
+		workerDialFailures.WithLabelValues("server").Inc()
+		w.reportFailure(req.ID, dialStatus(err), err)
+		if poolconn != nil {
+			poolconn.Close()
 		}
-		cross := &CrossConnection{
-			ID:    int(req.ID),
-			Conn1: reflconn.(*net.TCPConn),
-			Conn2: svrconn.(*net.TCPConn),
+		return
+	}
+	reflconn := poolconn
+	if reflconn == nil {
+		reflconn, err = w.openReflConn(req.Token)
+		if err != nil {
+			log.Printf("can't connect to reflector %v, %v", w.reflAddr, err)
+			workerDialFailures.WithLabelValues("reflector").Inc()
+			w.reportFailure(req.ID, api.CrossStatus_CROSS_REFL_DIAL_FAILED, err)
+			svrconn.Close()
+			return
 		}
-		go cross.Run()
+	}
+	w.runCross(req.ID, reflconn, svrconn)
+	w.reportChan <- &api.ReportWorkerCrossReq{
+		ID: req.ID,
+	}
+}
+
+// dialStatus returns the failure status of server dial error err
+func dialStatus(err error) api.CrossStatus {
+	var dnserr *net.DNSError
+	var neterr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return api.CrossStatus_CROSS_SERVER_REFUSED
+	case errors.Is(err, syscall.ENETUNREACH):
+		return api.CrossStatus_CROSS_SERVER_NET_UNREACHABLE
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.As(err, &dnserr):
+		return api.CrossStatus_CROSS_SERVER_HOST_UNREACHABLE
+	case errors.As(err, &neterr) && neterr.Timeout():
+		return api.CrossStatus_CROSS_SERVER_TIMEOUT
+	}
+	return api.CrossStatus_CROSS_SERVER_DIAL_FAILED
+}
+
+// reportFailure reports to reflector that cross connection id can't be created
+func (w *Worker) reportFailure(id uint32, st api.CrossStatus, err error) {
+	w.reportChan <- &api.ReportWorkerCrossReq{
+		ID:     id,
+		Status: st,
+		Error:  err.Error(),
+	}
+}
+
+// openReflConn returns a data connection or a multiplexed stream to reflector for the cross connection with
+// one-time token
+func (w *Worker) openReflConn(token []byte) (net.Conn, error) {
+	if len(w.muxSessions) > 0 {
+		return w.openMuxStream(token)
+	}
+	conn, err := w.dialRefl()
+	if err != nil {
+		return nil, err
+	}
+	if err = writePlainPreamble(conn, token); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialRefl creates a data connection to reflector, using TLS if tlsConf is set
+func (w *Worker) dialRefl() (net.Conn, error) {
+	conn, err := net.Dial("tcp", w.reflAddr)
+	if err != nil {
+		return nil, err
+	}
+	if w.tlsConf == nil {
+		return conn, nil
+	}
+	tlsconn := tls.Client(conn, w.tlsConf)
+	tlsconn.SetDeadline(time.Now().Add(preambleTimeout))
+	if err = tlsconn.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake with reflector failed, %w", err)
+	}
+	tlsconn.SetDeadline(time.Time{})
+	return tlsconn, nil
+}
+
+func (w *Worker) runCross(id uint32, reflconn, svrconn net.Conn) {
+	cross := &CrossConnection{
+		ID:          int(id),
+		Conn1:       reflconn,
+		Conn2:       svrconn,
+		StartTime:   time.Now(),
+		IdleTimeout: w.idleTimeout,
+		MaxLifetime: w.maxLifetime,
+	}
+	w.CCLock.Lock()
+	w.CrossConnections[int(id)] = cross
+	w.CCLock.Unlock()
+	go func() {
+		cross.Run()
 		w.CCLock.Lock()
-		w.CrossConnections[int(req.ID)] = cross
+		if w.CrossConnections[int(id)] == cross {
+			delete(w.CrossConnections, int(id))
+		}
 		w.CCLock.Unlock()
-		w.reportChan <- &api.ReportWorkerCrossReq{
-			ID:   req.ID,
-			Port: uint32(reflconn.LocalAddr().(*net.TCPAddr).Port),
+	}()
+}
+
+// maintainMuxSession keeps the i-th multiplexed data connection to reflector up
+func (w *Worker) maintainMuxSession(i int) {
+	for {
+		w.waitSignon()
+		sess, err := w.dialMuxSession()
+		if err != nil {
+			log.Printf("can't create multiplexed data connection to reflector %v, %v", w.reflAddr, err)
+			time.Sleep(muxRedialInterval)
+			continue
 		}
+		log.Printf("multiplexed data connection %d to reflector %v created", i, w.reflAddr)
+		w.muxLock.Lock()
+		w.muxSessions[i] = sess
+		w.muxLock.Unlock()
+		//reflector opens streams only to abort cross connections
+		sess.serve(nil)
+		log.Printf("multiplexed data connection %d to reflector %v closed", i, w.reflAddr)
+	}
+}
+
+func (w *Worker) dialMuxSession() (*muxSession, error) {
+	sessionID, dataToken := w.session()
+	if sessionID == "" {
+		return nil, fmt.Errorf("not signed on")
+	}
+	conn, err := w.dialRefl()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeMuxPreamble(conn, w.ID, dataToken); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sess, err := yamux.Client(conn, newMuxConfig())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newMuxSession(sess), nil
+}
+
+// openMuxStream opens a stream for the cross connection with one-time token over the next live
+// multiplexed data connection
+func (w *Worker) openMuxStream(token []byte) (net.Conn, error) {
+	w.muxLock.Lock()
+	var sess *muxSession
+	for range w.muxSessions {
+		w.nextMux = (w.nextMux + 1) % len(w.muxSessions)
+		if s := w.muxSessions[w.nextMux]; s != nil && !s.IsClosed() {
+			sess = s
+			break
+		}
+	}
+	w.muxLock.Unlock()
+	if sess == nil {
+		return nil, fmt.Errorf("no multiplexed data connection available")
+	}
+	return sess.openCrossStream(token)
+}
+
+// maintainPool keeps poolSize idle data connections to reflector while signed on, refilling when notified
+// via poolRefill or periodically
+func (w *Worker) maintainPool() {
+	for {
+		w.poolLock.Lock()
+		n := w.poolSize - len(w.idleConns)
+		w.poolLock.Unlock()
+		w.waitSignon()
+		for i := 0; i < n; i++ {
+			if err := w.addPoolConn(); err != nil {
+				log.Printf("can't create pool data connection to reflector %v, %v", w.reflAddr, err)
+				break
+			}
+		}
+		select {
+		case <-w.poolRefill:
+		case <-time.After(poolRefillInterval):
+		}
+	}
+}
+
+func (w *Worker) addPoolConn() error {
+	_, dataToken := w.session()
+	conn, err := w.dialRefl()
+	if err != nil {
+		return err
+	}
+	w.poolLock.Lock()
+	defer w.poolLock.Unlock()
+	w.nextPoolID++
+	if err = writePoolPreamble(conn, w.ID, dataToken, w.nextPoolID); err != nil {
+		conn.Close()
+		return err
+	}
+	w.idleConns[w.nextPoolID] = conn
+	return nil
+}
+
+// takePoolConn removes idle data connection id from pool and returns it, nil if not found
+func (w *Worker) takePoolConn(id uint32) net.Conn {
+	w.poolLock.Lock()
+	conn, ok := w.idleConns[id]
+	delete(w.idleConns, id)
+	w.poolLock.Unlock()
+	select {
+	case w.poolRefill <- struct{}{}:
+	default:
+	}
+	if !ok {
+		return nil
+	}
+	return conn
+}
+
+// flushPool closes all idle data connections in pool, and notifies maintainPool to refill
+func (w *Worker) flushPool() {
+	w.poolLock.Lock()
+	for id, conn := range w.idleConns {
+		conn.Close()
+		delete(w.idleConns, id)
+	}
+	w.poolLock.Unlock()
+	select {
+	case w.poolRefill <- struct{}{}:
+	default:
 	}
 }
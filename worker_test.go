@@ -0,0 +1,93 @@
+package main
+
+import (
+	"rproxy/api"
+	"sync"
+	"testing"
+)
+
+func TestServerAddr(t *testing.T) {
+	cases := []struct {
+		name      string
+		allowDest []string
+		allowAny  bool
+		req       *api.CreateWorkerCrossReq
+		want      string //empty means refused
+	}{
+		{
+			name: "own target",
+			req:  &api.CreateWorkerCrossReq{Tunnel: "db", Target: "10.9.9.9:22"},
+			want: "10.0.0.10:5432",
+		},
+		{
+			name: "svr without target",
+			req:  &api.CreateWorkerCrossReq{Tunnel: "x"},
+			want: "172.16.1.1:3000",
+		},
+		{
+			name: "tunnel target without allowdest",
+			req:  &api.CreateWorkerCrossReq{Tunnel: "x", Target: "10.9.9.9:22"},
+		},
+		{
+			name: "metadata address without allowdest",
+			req:  &api.CreateWorkerCrossReq{Target: "169.254.169.254:80"},
+		},
+		{
+			name:      "tunnel target in allowdest",
+			allowDest: []string{"10.0.0.0/8:22"},
+			req:       &api.CreateWorkerCrossReq{Tunnel: "x", Target: "10.9.9.9:22"},
+			want:      "10.9.9.9:22",
+		},
+		{
+			name:      "tunnel target not in allowdest",
+			allowDest: []string{"10.0.0.0/8:22"},
+			req:       &api.CreateWorkerCrossReq{Tunnel: "x", Target: "169.254.169.254:80"},
+		},
+		{
+			name:     "tunnel target with allowanydest",
+			allowAny: true,
+			req:      &api.CreateWorkerCrossReq{Tunnel: "x", Target: "10.9.9.9:22"},
+			want:     "10.9.9.9:22",
+		},
+		{
+			name: "dynamic without allowdest",
+			req:  &api.CreateWorkerCrossReq{Tunnel: "db", Target: "10.9.9.9:22", Dynamic: true},
+		},
+		{
+			name:      "dynamic in allowdest ignores own target",
+			allowDest: []string{"10.0.0.0/8:22"},
+			req:       &api.CreateWorkerCrossReq{Tunnel: "db", Target: "10.9.9.9:22", Dynamic: true},
+			want:      "10.9.9.9:22",
+		},
+		{
+			name:      "dynamic not in allowdest with allowanydest",
+			allowDest: []string{"10.0.0.0/8:22"},
+			allowAny:  true,
+			req:       &api.CreateWorkerCrossReq{Target: "169.254.169.254:80", Dynamic: true},
+		},
+		{
+			name:     "dynamic with allowanydest",
+			allowAny: true,
+			req:      &api.CreateWorkerCrossReq{Target: "10.9.9.9:22", Dynamic: true},
+			want:     "10.9.9.9:22",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := &Worker{
+				svrAddr:      "172.16.1.1:3000",
+				Targets:      map[string]string{"db": "10.0.0.10:5432"},
+				allowDest:    mustDestList(t, c.allowDest...),
+				allowAnyDest: c.allowAny,
+				targetLock:   new(sync.RWMutex),
+			}
+			got, err := w.serverAddr(c.req)
+			if c.want == "" && err == nil {
+				t.Errorf("got %q, expect refused", got)
+			}
+			if c.want != "" && (err != nil || got != c.want) {
+				t.Errorf("got %q and error %v, expect %q", got, err, c.want)
+			}
+		})
+	}
+}